@@ -0,0 +1,178 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"fmt"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountState - состояние адреса на блоке, по изменениям которого определяется активность.
+type accountState struct {
+	nonce   uint64
+	balance *big.Int
+	hasCode bool
+}
+
+func (s accountState) active() bool {
+	return s.nonce > 0 || s.balance.Sign() > 0 || s.hasCode
+}
+
+func blockTag(block uint64) string {
+	return fmt.Sprintf("0x%x", block)
+}
+
+// getNonce читает nonce адреса на блоке block ("latest" или hex).
+func getNonce(ctx context.Context, policy RetryPolicy, rpcURL string, accountAddress string, block string) (uint64, error) {
+	var nonceHex string
+
+	err := policy.Do(ctx, accountAddress+" | eth_getTransactionCount", func() error {
+		return callRpc(ctx, rpcURL, "eth_getTransactionCount", []interface{}{accountAddress, block}, &nonceHex)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	nonce, err := strconv.ParseUint(strings.TrimPrefix(nonceHex, "0x"), 16, 64)
+	if err != nil {
+		return 0, classify(ErrUpstreamSchemaChanged, fmt.Errorf("invalid nonce %q: %v", nonceHex, err))
+	}
+	return nonce, nil
+}
+
+// getAccountState читает nonce, баланс и (для контрактов) наличие кода на блоке block ("latest" или hex).
+func getAccountState(ctx context.Context, policy RetryPolicy, rpcURL string, accountAddress string, block string, withCode bool) (accountState, error) {
+	var balanceHex, code string
+
+	nonce, err := getNonce(ctx, policy, rpcURL, accountAddress, block)
+	if err != nil {
+		return accountState{}, err
+	}
+
+	err = policy.Do(ctx, accountAddress+" | eth_getBalance", func() error {
+		return callRpc(ctx, rpcURL, "eth_getBalance", []interface{}{accountAddress, block}, &balanceHex)
+	})
+	if err != nil {
+		return accountState{}, err
+	}
+
+	if withCode {
+		err = policy.Do(ctx, accountAddress+" | eth_getCode", func() error {
+			return callRpc(ctx, rpcURL, "eth_getCode", []interface{}{accountAddress, block}, &code)
+		})
+		if err != nil {
+			return accountState{}, err
+		}
+	}
+
+	balance, err := parseHexBigInt(balanceHex)
+	if err != nil {
+		return accountState{}, classify(ErrUpstreamSchemaChanged, err)
+	}
+
+	return accountState{nonce: nonce, balance: balance, hasCode: code != "" && code != "0x"}, nil
+}
+
+// searchFirstBlock находит наименьший блок в [low, high], для которого predicate истинен.
+// predicate должен быть монотонным, а для high - истинным.
+func searchFirstBlock(low uint64, high uint64, predicate func(block uint64) (bool, error)) (uint64, error) {
+	for low < high {
+		middle := low + (high-low)/2
+		ok, err := predicate(middle)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			high = middle
+		} else {
+			low = middle + 1
+		}
+	}
+	return low, nil
+}
+
+// collectChainActivity собирает статистику одной сети. Блоки активности ищутся бинарным поиском
+// по историческому состоянию, поэтому условие поиска должно быть монотонным: первый блок - где адрес
+// стал активным, последний - где nonce достиг текущего значения (nonce только растет).
+func collectChainActivity(ctx context.Context, policy RetryPolicy, chainName string, rpcURL string, accountAddress string) customTypes.ChainActivity {
+	activity := customTypes.ChainActivity{ChainName: chainName, UpdatedAt: time.Now().Unix()}
+
+	latestBlock, err := getBlockNumber(ctx, policy, rpcURL)
+	if err != nil {
+		activity.Error = err.Error()
+		return activity
+	}
+
+	current, err := getAccountState(ctx, policy, rpcURL, accountAddress, blockTag(latestBlock), true)
+	if err != nil {
+		activity.Error = err.Error()
+		return activity
+	}
+
+	activity.Nonce = current.nonce
+	activity.IsContract = current.hasCode
+	if !current.active() {
+		return activity
+	}
+
+	stateAt := func(block uint64) (accountState, error) {
+		return getAccountState(ctx, policy, rpcURL, accountAddress, blockTag(block), current.hasCode)
+	}
+
+	firstBlock, err := searchFirstBlock(0, latestBlock, func(block uint64) (bool, error) {
+		state, err := stateAt(block)
+		return state.active(), err
+	})
+	if err != nil {
+		activity.Error = fmt.Sprintf("activity blocks (archive node required): %v", err)
+		return activity
+	}
+
+	activity.FirstActivityBlock = firstBlock
+	if current.nonce == 0 {
+		return activity
+	}
+
+	lastBlock, err := searchFirstBlock(firstBlock, latestBlock, func(block uint64) (bool, error) {
+		nonce, err := getNonce(ctx, policy, rpcURL, accountAddress, blockTag(block))
+		return nonce >= current.nonce, err
+	})
+	if err != nil {
+		activity.Error = fmt.Sprintf("activity blocks (archive node required): %v", err)
+		return activity
+	}
+
+	activity.LastActivityBlock = lastBlock
+	return activity
+}
+
+// AttachActivity добавляет в ответ статистику активности EVM адреса во всех сетях rpc_config,
+// если она включена в activity_config. Ошибки сетей сохраняются в самой статистике и не делают
+// результат проверки неполным.
+func AttachActivity(ctx context.Context, response *customTypes.ServerResponse, config customTypes.ConfigStruct) {
+	if !config.ActivityConfig.Enabled || response == nil || response.ChainFamily != utils.FamilyEVM {
+		return
+	}
+
+	if len(config.RpcConfig.Chains) == 0 {
+		log.Printf("%s | Activity Stats Skipped: no rpc chains configured", response.WalletAddress)
+		return
+	}
+
+	policy := GetRetryPolicy(config, "evm_rpc")
+	activity := make([]customTypes.ChainActivity, 0, len(config.RpcConfig.Chains))
+
+	for _, chainName := range sortedChainNames(config.RpcConfig.Chains) {
+		if ctx.Err() != nil {
+			return
+		}
+		activity = append(activity, collectChainActivity(ctx, policy, chainName, config.RpcConfig.Chains[chainName].RpcURL, response.WalletAddress))
+	}
+
+	response.Activity = activity
+}
@@ -0,0 +1,309 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	// Approval(address,address,uint256) - общий для ERC-20 и ERC-721 (у ERC-721 tokenId тоже indexed)
+	approvalTopic = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	// ApprovalForAll(address,address,bool)
+	approvalForAllTopic = "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
+
+	ApprovalERC20          = "erc20"
+	ApprovalERC721         = "erc721"
+	ApprovalApprovalForAll = "approval_for_all"
+)
+
+var (
+	// allowance(address,address)
+	erc20AllowanceSelector = []byte{0xdd, 0x62, 0xed, 0x3e}
+	// getApproved(uint256)
+	erc721GetApprovedSelector = []byte{0x08, 0x18, 0x12, 0xfc}
+	// isApprovedForAll(address,address)
+	isApprovedForAllSelector = []byte{0xe9, 0x85, 0xe9, 0xc5}
+)
+
+// unlimitedAllowance - порог "безлимитного" allowance. Не MaxUint256: токены вроде UNI и COMP
+// хранят allowance в uint96 и при approve(max) записывают 2^96-1.
+var unlimitedAllowance = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 96), big.NewInt(1))
+
+// Встроенный список доверенных spender (ключи в нижнем регистре). Дополняется approval_config.known_spenders.
+var defaultKnownSpenders = map[string]string{
+	"0x000000000022d473030f116ddee9f6b43ac78ba3": "Uniswap Permit2",
+	"0x7a250d5630b4cf539739df2c5dacb4c659f2488d": "Uniswap V2 Router",
+	"0xe592427a0aece92de3edee1f18e0157c05861564": "Uniswap V3 Router",
+	"0x68b3465833fb72a70ecdf485e0e4c7bd8665fc45": "Uniswap SwapRouter02",
+	"0x3fc91a3afd70395cd496c647d5a6cc9d4b2b7fad": "Uniswap Universal Router",
+	"0x1111111254eeb25477b68fb85ed929f73a960582": "1inch Router V5",
+	"0x111111125421ca6dc452d289314280a0f8842a65": "1inch Router V6",
+	"0x00000000000000adc04c56bf30ac9d3c0aaf14dc": "OpenSea Seaport 1.5",
+	"0x1e0049783f008a0085193e00003d00cd54003c71": "OpenSea Conduit",
+}
+
+type ethLog struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	BlockNumber string   `json:"blockNumber"`
+}
+
+func getBlockNumber(ctx context.Context, policy RetryPolicy, rpcURL string) (uint64, error) {
+	var blockHex string
+	err := policy.Do(ctx, "eth_blockNumber", func() error {
+		return callRpc(ctx, rpcURL, "eth_blockNumber", []interface{}{}, &blockHex)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimPrefix(blockHex, "0x"), 16, 64)
+}
+
+// getApprovalLogs читает логи Approval и ApprovalForAll, где владелец - accountAddress.
+// При blockRange > 0 диапазон разбивается на части: публичные ноды ограничивают eth_getLogs.
+func getApprovalLogs(ctx context.Context, policy RetryPolicy, rpcURL string, accountAddress string, fromBlock uint64, toBlock uint64, blockRange uint64) ([]ethLog, error) {
+	ownerTopic := "0x" + hex.EncodeToString(common.LeftPadBytes(common.HexToAddress(accountAddress).Bytes(), 32))
+
+	var logs []ethLog
+	for start := fromBlock; start <= toBlock; {
+		end := toBlock
+		if blockRange > 0 && start+blockRange-1 < toBlock {
+			end = start + blockRange - 1
+		}
+
+		filter := map[string]interface{}{
+			"fromBlock": fmt.Sprintf("0x%x", start),
+			"toBlock":   fmt.Sprintf("0x%x", end),
+			"topics":    []interface{}{[]string{approvalTopic, approvalForAllTopic}, ownerTopic},
+		}
+
+		var chunkLogs []ethLog
+		err := policy.Do(ctx, fmt.Sprintf("%s | eth_getLogs %d-%d", accountAddress, start, end), func() error {
+			return callRpc(ctx, rpcURL, "eth_getLogs", []interface{}{filter}, &chunkLogs)
+		})
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, chunkLogs...)
+		start = end + 1
+	}
+	return logs, nil
+}
+
+func topicAddress(topic string) string {
+	return strings.ToLower(common.HexToAddress(topic).Hex())
+}
+
+// approvalCandidates оставляет последнее событие для каждой пары (токен, spender[, tokenId]).
+// Текущее состояние все равно подтверждается вызовами контрактов, логи лишь дают список пар.
+func approvalCandidates(chainName string, logs []ethLog) []customTypes.TokenApproval {
+	byKey := make(map[string]int)
+	var candidates []customTypes.TokenApproval
+
+	for _, entry := range logs {
+		if len(entry.Topics) < 3 {
+			continue
+		}
+
+		approval := customTypes.TokenApproval{
+			ChainName: chainName,
+			Token:     strings.ToLower(entry.Address),
+			Spender:   topicAddress(entry.Topics[2]),
+		}
+		// approve на нулевой адрес - отзыв разрешения (ERC-721 сбрасывает его так же при переводе)
+		if common.HexToAddress(approval.Spender) == (common.Address{}) {
+			continue
+		}
+		if blockNumber, err := strconv.ParseUint(strings.TrimPrefix(entry.BlockNumber, "0x"), 16, 64); err == nil {
+			approval.BlockNumber = blockNumber
+		}
+
+		switch {
+		case strings.EqualFold(entry.Topics[0], approvalForAllTopic):
+			approval.Standard = ApprovalApprovalForAll
+		case len(entry.Topics) == 4:
+			tokenID, err := parseHexBigInt(entry.Topics[3])
+			if err != nil {
+				continue
+			}
+			approval.Standard = ApprovalERC721
+			approval.TokenID = tokenID.String()
+		default:
+			approval.Standard = ApprovalERC20
+		}
+
+		key := strings.Join([]string{approval.Token, approval.Standard, approval.Spender, approval.TokenID}, "|")
+		if index, exists := byKey[key]; exists {
+			candidates[index] = approval
+			continue
+		}
+		byKey[key] = len(candidates)
+		candidates = append(candidates, approval)
+	}
+	return candidates
+}
+
+func encodeApprovalCheck(accountAddress string, approval customTypes.TokenApproval) []byte {
+	owner := common.LeftPadBytes(common.HexToAddress(accountAddress).Bytes(), 32)
+	spender := common.LeftPadBytes(common.HexToAddress(approval.Spender).Bytes(), 32)
+
+	switch approval.Standard {
+	case ApprovalERC721:
+		tokenID, _ := new(big.Int).SetString(approval.TokenID, 10)
+		return append(append([]byte{}, erc721GetApprovedSelector...), common.LeftPadBytes(tokenID.Bytes(), 32)...)
+	case ApprovalApprovalForAll:
+		return append(append(append([]byte{}, isApprovedForAllSelector...), owner...), spender...)
+	}
+	return append(append(append([]byte{}, erc20AllowanceSelector...), owner...), spender...)
+}
+
+// confirmApprovals проверяет текущее состояние разрешений через Multicall3 и возвращает только действующие.
+func confirmApprovals(ctx context.Context, policy RetryPolicy, chainConfig customTypes.RpcChainConfig, accountAddress string, candidates []customTypes.TokenApproval) ([]customTypes.TokenApproval, error) {
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	calls := make([]multicallCall, len(candidates))
+	for i, candidate := range candidates {
+		calls[i] = multicallCall{
+			Target:       common.HexToAddress(candidate.Token),
+			AllowFailure: true,
+			CallData:     encodeApprovalCheck(accountAddress, candidate),
+		}
+	}
+
+	results, err := callMulticall(ctx, policy, chainConfig.RpcURL, chainConfig.Multicall, calls, chainConfig.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var active []customTypes.TokenApproval
+	for i, result := range results {
+		value, err := decodeUint256(result.ReturnData)
+		if !result.Success || err != nil {
+			continue
+		}
+
+		approval := candidates[i]
+		switch approval.Standard {
+		case ApprovalERC20:
+			if value.Sign() == 0 {
+				continue
+			}
+			approval.Allowance = value.String()
+			approval.Unlimited = value.Cmp(unlimitedAllowance) >= 0
+		case ApprovalERC721:
+			approved := common.BytesToAddress(result.ReturnData)
+			if approved == (common.Address{}) || strings.ToLower(approved.Hex()) != approval.Spender {
+				continue
+			}
+		case ApprovalApprovalForAll:
+			if value.Sign() == 0 {
+				continue
+			}
+			approval.Unlimited = true
+		}
+		active = append(active, approval)
+	}
+	return active, nil
+}
+
+// AuditApprovals собирает действующие approve кошелька во всех сетях rpc_config. Безлимитные
+// разрешения неизвестным spender помечаются как Risky и идут первыми в списке.
+func AuditApprovals(ctx context.Context, accountData string, config customTypes.ConfigStruct) (*customTypes.ApprovalsReport, error) {
+	accountAddress, err := getAccountAddress(accountData, evmFamilies)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := config.RpcConfig.Chains
+	if len(chains) == 0 {
+		return nil, classify(ErrInvalidConfig, fmt.Errorf("no rpc chains configured"))
+	}
+
+	policy := GetRetryPolicy(config, "evm_rpc")
+	approvalConfig := config.ApprovalConfig
+
+	knownSpenders := make(map[string]string, len(defaultKnownSpenders)+len(approvalConfig.KnownSpenders))
+	for spender, name := range defaultKnownSpenders {
+		knownSpenders[spender] = name
+	}
+	for spender, name := range approvalConfig.KnownSpenders {
+		knownSpenders[strings.ToLower(spender)] = name
+	}
+
+	report := &customTypes.ApprovalsReport{WalletAddress: accountAddress, Approvals: make([]customTypes.TokenApproval, 0)}
+	failedChains := 0
+	var lastErr error
+
+	for _, chainName := range sortedChainNames(chains) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		chainConfig := chains[chainName]
+		approvals, err := auditChainApprovals(ctx, policy, chainName, chainConfig, approvalConfig, accountAddress)
+		if err != nil {
+			log.Printf("%s | %s | Failed To Audit Approvals: %v", accountAddress, chainName, err)
+			report.Errors = append(report.Errors, fmt.Sprintf("%s | %v", chainName, err))
+			failedChains++
+			lastErr = err
+			continue
+		}
+
+		symbols := make(map[string]string, len(chainConfig.Tokens))
+		for _, token := range chainConfig.Tokens {
+			symbols[strings.ToLower(token.Contract)] = token.Symbol
+		}
+
+		for _, approval := range approvals {
+			approval.TokenSymbol = symbols[approval.Token]
+			approval.SpenderName = knownSpenders[approval.Spender]
+
+			approval.Risky = approval.Unlimited && approval.SpenderName == ""
+			if approval.Risky {
+				report.RiskyCount++
+			}
+			report.Approvals = append(report.Approvals, approval)
+		}
+	}
+
+	if failedChains == len(chains) {
+		return nil, classify(ErrUpstreamUnavailable, fmt.Errorf("%s | failed to audit approvals on all rpc chains: %w", accountAddress, lastErr))
+	}
+
+	sort.SliceStable(report.Approvals, func(i, j int) bool {
+		return report.Approvals[i].Risky && !report.Approvals[j].Risky
+	})
+
+	log.Printf("%s | Found %d Approvals (%d Risky)", accountAddress, len(report.Approvals), report.RiskyCount)
+	return report, nil
+}
+
+func auditChainApprovals(ctx context.Context, policy RetryPolicy, chainName string, chainConfig customTypes.RpcChainConfig, approvalConfig customTypes.ApprovalConfig, accountAddress string) ([]customTypes.TokenApproval, error) {
+	latestBlock, err := getBlockNumber(ctx, policy, chainConfig.RpcURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fromBlock := approvalConfig.FromBlock[chainName]
+	if fromBlock > latestBlock {
+		return nil, nil
+	}
+
+	logs, err := getApprovalLogs(ctx, policy, chainConfig.RpcURL, accountAddress, fromBlock, latestBlock, approvalConfig.BlockRange)
+	if err != nil {
+		return nil, err
+	}
+
+	return confirmApprovals(ctx, policy, chainConfig, accountAddress, approvalCandidates(chainName, logs))
+}
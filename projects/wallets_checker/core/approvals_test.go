@@ -0,0 +1,118 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"debank_checker_v3/customTypes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func addressTopic(address string) string {
+	return "0x" + hex.EncodeToString(common.LeftPadBytes(common.HexToAddress(address).Bytes(), 32))
+}
+
+func TestAuditApprovalsConfirmsCurrentState(t *testing.T) {
+	const (
+		wallet       = "0x1111111111111111111111111111111111111111"
+		token        = "0x2222222222222222222222222222222222222222"
+		revokedToken = "0x3333333333333333333333333333333333333333"
+		nft          = "0x4444444444444444444444444444444444444444"
+		drainer      = "0x5555555555555555555555555555555555555555"
+		marketplace  = "0x6666666666666666666666666666666666666666"
+		permit2      = "0x000000000022d473030f116ddee9f6b43ac78ba3"
+		zeroAddress  = "0x0000000000000000000000000000000000000000"
+	)
+
+	owner := addressTopic(wallet)
+	logs := []map[string]interface{}{
+		{"address": token, "topics": []string{approvalTopic, owner, addressTopic(drainer)}, "blockNumber": "0x10"},
+		{"address": token, "topics": []string{approvalTopic, owner, addressTopic(permit2)}, "blockNumber": "0x11"},
+		{"address": revokedToken, "topics": []string{approvalTopic, owner, addressTopic(drainer)}, "blockNumber": "0x12"},
+		// Токен 7 передан после approve, getApproved уже вернет нулевой адрес
+		{"address": nft, "topics": []string{approvalTopic, owner, addressTopic(marketplace), "0x7"}, "blockNumber": "0x13"},
+		{"address": nft, "topics": []string{approvalTopic, owner, addressTopic(marketplace), "0x8"}, "blockNumber": "0x14"},
+		// Отзыв разрешения - approve на нулевой адрес, проверять его нечего
+		{"address": nft, "topics": []string{approvalTopic, owner, addressTopic(zeroAddress), "0x9"}, "blockNumber": "0x15"},
+		{"address": nft, "topics": []string{approvalForAllTopic, owner, addressTopic(zeroAddress)}, "blockNumber": "0x16"},
+	}
+
+	var checkedCalls int
+	stub := newRpcStub(t, func(method string, params []json.RawMessage) (interface{}, *rpcError) {
+		switch method {
+		case "eth_blockNumber":
+			return "0x100", nil
+		case "eth_getLogs":
+			return logs, nil
+		case "eth_call":
+			return multicallHandler(t, params, func(target common.Address, callData []byte) multicallResult {
+				checkedCalls++
+				contract := strings.ToLower(target.Hex())
+				selector, args := callData[:4], callData[4:]
+
+				switch {
+				case bytes.Equal(selector, erc20AllowanceSelector):
+					spender := strings.ToLower(common.BytesToAddress(args[32:64]).Hex())
+					switch {
+					case contract == token && spender == drainer:
+						return uint256Result(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)))
+					case contract == token && spender == permit2:
+						return uint256Result(big.NewInt(1_000_000))
+					}
+					return uint256Result(new(big.Int))
+				case bytes.Equal(selector, erc721GetApprovedSelector):
+					if new(big.Int).SetBytes(args).Int64() == 8 {
+						return multicallResult{Success: true, ReturnData: common.LeftPadBytes(common.HexToAddress(marketplace).Bytes(), 32)}
+					}
+					return multicallResult{Success: true, ReturnData: make([]byte, 32)}
+				}
+				t.Errorf("unexpected call %x to %s", selector, contract)
+				return multicallResult{}
+			}), nil
+		}
+		return nil, &rpcError{Code: -32601, Message: "method not found"}
+	})
+
+	config := customTypes.ConfigStruct{
+		RpcConfig: customTypes.RpcConfig{Chains: map[string]customTypes.RpcChainConfig{
+			"eth": {RpcURL: stub.URL, Tokens: []customTypes.RpcTokenConfig{{Contract: token, Symbol: "TKN", Decimals: 18}}},
+		}},
+		RetryConfig: map[string]customTypes.RetryConfig{"evm_rpc": {MaxAttempts: 1}},
+	}
+
+	report, err := AuditApprovals(context.Background(), wallet, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Кандидаты с нулевым spender не проверяются
+	if checkedCalls != 5 {
+		t.Errorf("checked %d approvals, want 5", checkedCalls)
+	}
+
+	got := make([]string, len(report.Approvals))
+	for i, approval := range report.Approvals {
+		got[i] = fmt.Sprintf("%s %s %s %s risky=%t", approval.Standard, approval.Token, approval.Spender, approval.TokenID, approval.Risky)
+	}
+	want := []string{
+		fmt.Sprintf("%s %s %s  risky=true", ApprovalERC20, token, drainer),
+		fmt.Sprintf("%s %s %s  risky=false", ApprovalERC20, token, permit2),
+		fmt.Sprintf("%s %s %s 8 risky=false", ApprovalERC721, nft, marketplace),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("approvals:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if report.RiskyCount != 1 {
+		t.Errorf("risky count = %d, want 1", report.RiskyCount)
+	}
+	if report.Approvals[0].TokenSymbol != "TKN" || report.Approvals[1].SpenderName != "Uniswap Permit2" {
+		t.Errorf("approval metadata = %+v, want token symbol and known spender name", report.Approvals[:2])
+	}
+}
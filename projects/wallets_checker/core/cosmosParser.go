@@ -0,0 +1,470 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	cosmosPoolLiquid  = "Liquid"
+	cosmosPoolStaked  = "Staked"
+	cosmosPoolRewards = "Rewards"
+
+	cosmosPageLimit = "1000"
+)
+
+var cosmosFamilies = []string{utils.FamilyCosmos}
+
+type cosmosChainDefaults struct {
+	Prefix      string
+	NativeDenom string
+}
+
+// Префиксы адресов и нативные деномы известных сетей. Ключ - имя сети в cosmos_config.chains.
+var defaultCosmosChains = map[string]cosmosChainDefaults{
+	"cosmoshub": {Prefix: "cosmos", NativeDenom: "uatom"},
+	"osmosis":   {Prefix: "osmo", NativeDenom: "uosmo"},
+	"celestia":  {Prefix: "celestia", NativeDenom: "utia"},
+	"injective": {Prefix: "inj", NativeDenom: "inj"},
+	"neutron":   {Prefix: "neutron", NativeDenom: "untrn"},
+	"stride":    {Prefix: "stride", NativeDenom: "ustrd"},
+	"kava":      {Prefix: "kava", NativeDenom: "ukava"},
+	"sei":       {Prefix: "sei", NativeDenom: "usei"},
+	"axelar":    {Prefix: "axelar", NativeDenom: "uaxl"},
+}
+
+type cosmosDenomInfo struct {
+	Symbol   string
+	Decimals int
+	// Chain - сеть, для которой деном нативный. По ней ищется цена, в том числе для IBC копий.
+	Chain string
+}
+
+var defaultCosmosDenoms = map[string]cosmosDenomInfo{
+	"uatom": {Symbol: "ATOM", Decimals: 6, Chain: "cosmoshub"},
+	"uosmo": {Symbol: "OSMO", Decimals: 6, Chain: "osmosis"},
+	"utia":  {Symbol: "TIA", Decimals: 6, Chain: "celestia"},
+	"inj":   {Symbol: "INJ", Decimals: 18, Chain: "injective"},
+	"untrn": {Symbol: "NTRN", Decimals: 6, Chain: "neutron"},
+	"ustrd": {Symbol: "STRD", Decimals: 6, Chain: "stride"},
+	"ukava": {Symbol: "KAVA", Decimals: 6, Chain: "kava"},
+	"usei":  {Symbol: "SEI", Decimals: 6, Chain: "sei"},
+	"uaxl":  {Symbol: "AXL", Decimals: 6, Chain: "axelar"},
+}
+
+type cosmosProvider struct{}
+
+func init() {
+	RegisterProvider(cosmosProvider{})
+}
+
+func (cosmosProvider) Name() string {
+	return "cosmos_lcd"
+}
+
+func (cosmosProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Pools: true, Families: cosmosFamilies}
+}
+
+func (cosmosProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	priceSource, err := GetPriceSource(opts.Config.PriceConfig)
+	if err != nil {
+		log.Printf("Failed To Init Price Source: %v", err)
+	}
+	return ParseCosmosAccount(ctx, accountData, opts.Config.CosmosConfig, GetRetryPolicy(opts.Config, "cosmos_lcd"), priceSource)
+}
+
+// cosmosChain - настройки сети с подставленными значениями по умолчанию.
+type cosmosChain struct {
+	name   string
+	config customTypes.CosmosChainConfig
+}
+
+func resolveCosmosChain(name string, config customTypes.CosmosChainConfig) cosmosChain {
+	defaults := defaultCosmosChains[name]
+	if config.Prefix == "" {
+		config.Prefix = defaults.Prefix
+	}
+	if config.NativeDenom == "" {
+		config.NativeDenom = defaults.NativeDenom
+	}
+	return cosmosChain{name: name, config: config}
+}
+
+// doLcdRequest выполняет GET запрос к LCD (REST) эндпоинту Cosmos SDK.
+func doLcdRequest(ctx context.Context, policy RetryPolicy, lcdURL string, path string, result interface{}) error {
+	return policy.Do(ctx, "lcd "+path, func() error {
+		client, err := GetClient(nil)
+		if err != nil {
+			return err
+		}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI(strings.TrimSuffix(lcdURL, "/") + path)
+		req.Header.SetMethod(fasthttp.MethodGet)
+		req.Header.Set("accept", "application/json")
+
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+
+		if err := doWithContext(ctx, client, req, resp); err != nil {
+			return fmt.Errorf("%s request error: %w", path, err)
+		}
+
+		// 501 - эндпоинт не реализован в этой версии SDK, повторять бесполезно
+		if resp.StatusCode() == fasthttp.StatusNotImplemented {
+			return permanent(classify(ErrUpstreamUnavailable, fmt.Errorf("%s is not implemented by lcd", path)))
+		}
+
+		if resp.StatusCode() != fasthttp.StatusOK {
+			return statusError(fmt.Errorf("%s unexpected status code %d: %s", path, resp.StatusCode(), resp.Body()),
+				resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
+		}
+
+		if err := json.Unmarshal(resp.Body(), result); err != nil {
+			return permanent(classify(ErrUpstreamSchemaChanged, fmt.Errorf("%s failed to parse JSON response: %v", path, err)))
+		}
+
+		return nil
+	})
+}
+
+type cosmosCoin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+func getCosmosBalances(ctx context.Context, policy RetryPolicy, lcdURL string, accountAddress string) ([]cosmosCoin, error) {
+	var responseData struct {
+		Balances []cosmosCoin `json:"balances"`
+	}
+	path := "/cosmos/bank/v1beta1/balances/" + accountAddress + "?pagination.limit=" + cosmosPageLimit
+	if err := doLcdRequest(ctx, policy, lcdURL, path, &responseData); err != nil {
+		return nil, err
+	}
+	return responseData.Balances, nil
+}
+
+func getCosmosDelegations(ctx context.Context, policy RetryPolicy, lcdURL string, accountAddress string) ([]cosmosCoin, error) {
+	var responseData struct {
+		DelegationResponses []struct {
+			Balance cosmosCoin `json:"balance"`
+		} `json:"delegation_responses"`
+	}
+	path := "/cosmos/staking/v1beta1/delegations/" + accountAddress + "?pagination.limit=" + cosmosPageLimit
+	if err := doLcdRequest(ctx, policy, lcdURL, path, &responseData); err != nil {
+		return nil, err
+	}
+
+	coins := make([]cosmosCoin, 0, len(responseData.DelegationResponses))
+	for _, delegation := range responseData.DelegationResponses {
+		coins = append(coins, delegation.Balance)
+	}
+	return coins, nil
+}
+
+// getCosmosRewards возвращает сумму невыведенных наград по всем валидаторам (DecCoin с дробной частью).
+func getCosmosRewards(ctx context.Context, policy RetryPolicy, lcdURL string, accountAddress string) ([]cosmosCoin, error) {
+	var responseData struct {
+		Total []cosmosCoin `json:"total"`
+	}
+	path := "/cosmos/distribution/v1beta1/delegators/" + accountAddress + "/rewards"
+	if err := doLcdRequest(ctx, policy, lcdURL, path, &responseData); err != nil {
+		return nil, err
+	}
+	return responseData.Total, nil
+}
+
+var (
+	ibcDenomsMu sync.Mutex
+	ibcDenoms   = make(map[string]string)
+)
+
+// resolveIbcDenom возвращает базовый деноминатор IBC токена (ibc/<hash> -> uatom).
+// Старые версии ibc-go отдают denom_traces, новые - denoms. Результат кэшируется на время работы.
+func resolveIbcDenom(ctx context.Context, policy RetryPolicy, lcdURL string, denom string) (string, error) {
+	cacheKey := lcdURL + "|" + denom
+
+	ibcDenomsMu.Lock()
+	baseDenom, exists := ibcDenoms[cacheKey]
+	ibcDenomsMu.Unlock()
+	if exists {
+		return baseDenom, nil
+	}
+
+	hash := strings.TrimPrefix(denom, "ibc/")
+
+	var traceData struct {
+		DenomTrace struct {
+			BaseDenom string `json:"base_denom"`
+		} `json:"denom_trace"`
+	}
+	err := doLcdRequest(ctx, policy, lcdURL, "/ibc/apps/transfer/v1/denom_traces/"+hash, &traceData)
+	baseDenom = traceData.DenomTrace.BaseDenom
+
+	if err != nil || baseDenom == "" {
+		var denomData struct {
+			Denom struct {
+				Base string `json:"base"`
+			} `json:"denom"`
+		}
+		if denomErr := doLcdRequest(ctx, policy, lcdURL, "/ibc/apps/transfer/v1/denoms/"+hash, &denomData); denomErr != nil {
+			if err == nil {
+				err = denomErr
+			}
+			return "", err
+		}
+		baseDenom = denomData.Denom.Base
+	}
+
+	if baseDenom == "" {
+		return "", classify(ErrUpstreamSchemaChanged, fmt.Errorf("empty base denom for %s", denom))
+	}
+
+	ibcDenomsMu.Lock()
+	ibcDenoms[cacheKey] = baseDenom
+	ibcDenomsMu.Unlock()
+	return baseDenom, nil
+}
+
+// denomInfo определяет символ, decimals и ключ цены денома. Порядок: настройки сети,
+// известные деномы, затем эвристика по префиксу (u - 6 знаков, a - 18 знаков).
+func denomInfo(chain cosmosChain, denom string) (cosmosDenomInfo, customTypes.PriceKey) {
+	for _, configured := range chain.config.Denoms {
+		if configured.Denom == denom {
+			return cosmosDenomInfo{Symbol: configured.Symbol, Decimals: configured.Decimals}, NewPriceKey(chain.name, denom)
+		}
+	}
+
+	if known, exists := defaultCosmosDenoms[denom]; exists {
+		return known, NewPriceKey(known.Chain, "")
+	}
+
+	// Нативный деном сети без описания - цена ищется как у нативной монеты
+	priceKey := NewPriceKey(chain.name, denom)
+	if denom == chain.config.NativeDenom {
+		priceKey = NewPriceKey(chain.name, "")
+	}
+
+	switch {
+	case strings.Contains(denom, "/"):
+		return cosmosDenomInfo{Symbol: denom, Decimals: 6}, priceKey
+	case len(denom) > 1 && denom[0] == 'u':
+		return cosmosDenomInfo{Symbol: strings.ToUpper(denom[1:]), Decimals: 6}, priceKey
+	case len(denom) > 1 && denom[0] == 'a':
+		return cosmosDenomInfo{Symbol: strings.ToUpper(denom[1:]), Decimals: 18}, priceKey
+	}
+	return cosmosDenomInfo{Symbol: strings.ToUpper(denom), Decimals: 0}, priceKey
+}
+
+type cosmosHolding struct {
+	category string
+	symbol   string
+	amount   *big.Float
+	priceKey customTypes.PriceKey
+	// unpriced - IBC деноминатор не разрешился: amount в минимальных единицах, цены нет
+	unpriced bool
+}
+
+// collectCosmosHoldings переводит монеты одной категории в человекочитаемые количества.
+// Монеты одного денома (делегации разным валидаторам) суммируются. IBC токены, базовый деноминатор
+// которых не удалось получить, возвращаются как есть (ibc/<hash>, без decimals и цены) вместе с ошибкой.
+func collectCosmosHoldings(ctx context.Context, policy RetryPolicy, chain cosmosChain, category string, coins []cosmosCoin) ([]cosmosHolding, []error) {
+	byDenom := make(map[string]*cosmosHolding)
+	var order []string
+	var errs []error
+
+	for _, coin := range coins {
+		rawAmount, ok := new(big.Float).SetString(coin.Amount)
+		if !ok || rawAmount.Sign() <= 0 {
+			continue
+		}
+
+		denom := coin.Denom
+		if strings.HasPrefix(denom, "ibc/") {
+			baseDenom, err := resolveIbcDenom(ctx, policy, chain.config.LcdURL, denom)
+			if err != nil {
+				log.Printf("%s | Failed To Resolve IBC Denom %s: %v", chain.name, denom, err)
+				if existing, exists := byDenom[denom]; exists {
+					existing.amount.Add(existing.amount, rawAmount)
+					continue
+				}
+				byDenom[denom] = &cosmosHolding{category: category, symbol: denom, amount: rawAmount, unpriced: true}
+				order = append(order, denom)
+				errs = append(errs, fmt.Errorf("%s | %s: unresolved ibc denom %s, amount is raw and not priced: %w", chain.name, strings.ToLower(category), denom, err))
+				continue
+			}
+			denom = baseDenom
+		}
+
+		info, priceKey := denomInfo(chain, denom)
+		amount := rawAmount
+		if info.Decimals > 0 {
+			divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(info.Decimals)), nil))
+			amount = new(big.Float).Quo(rawAmount, divisor)
+		}
+
+		if existing, exists := byDenom[denom]; exists {
+			existing.amount.Add(existing.amount, amount)
+			continue
+		}
+		byDenom[denom] = &cosmosHolding{category: category, symbol: info.Symbol, amount: amount, priceKey: priceKey}
+		order = append(order, denom)
+	}
+
+	holdings := make([]cosmosHolding, 0, len(order))
+	for _, denom := range order {
+		holdings = append(holdings, *byDenom[denom])
+	}
+	return holdings, errs
+}
+
+// ParseCosmosAccount читает свободные, застейканные средства и невыведенные награды через LCD
+// во всех настроенных сетях с префиксом адреса. Каждая категория - отдельный протокол в pools.
+func ParseCosmosAccount(ctx context.Context, accountData string, config customTypes.CosmosConfig, policy RetryPolicy, priceSource PriceSource) (*customTypes.ServerResponse, error) {
+	accountAddress, err := getAccountAddress(accountData, cosmosFamilies)
+	if err != nil {
+		return nil, err
+	}
+	prefix := accountAddress[:strings.LastIndexByte(accountAddress, '1')]
+
+	var chains []cosmosChain
+	for name, chainConfig := range config.Chains {
+		if chain := resolveCosmosChain(name, chainConfig); chain.config.Prefix == prefix && chain.config.LcdURL != "" {
+			chains = append(chains, chain)
+		}
+	}
+	if len(chains) == 0 {
+		return nil, classify(ErrInvalidConfig, fmt.Errorf("no lcd configured for %s addresses", prefix))
+	}
+	sort.Slice(chains, func(i, j int) bool {
+		return chains[i].name < chains[j].name
+	})
+
+	chainHoldings := make(map[string][]cosmosHolding)
+	var chainErrors []error
+	var denomErrors []error
+	failedChains := 0
+
+	for _, chain := range chains {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		lcdURL := chain.config.LcdURL
+		failed := 0
+		var holdings []cosmosHolding
+
+		for _, part := range []struct {
+			category string
+			fetch    func(context.Context, RetryPolicy, string, string) ([]cosmosCoin, error)
+		}{
+			{cosmosPoolLiquid, getCosmosBalances},
+			{cosmosPoolStaked, getCosmosDelegations},
+			{cosmosPoolRewards, getCosmosRewards},
+		} {
+			coins, err := part.fetch(ctx, policy, lcdURL, accountAddress)
+			if err != nil {
+				log.Printf("%s | %s | Failed To Get %s Balance: %v", accountAddress, chain.name, part.category, err)
+				chainErrors = append(chainErrors, fmt.Errorf("%s | %s: %w", chain.name, strings.ToLower(part.category), err))
+				failed++
+				continue
+			}
+			collected, errs := collectCosmosHoldings(ctx, policy, chain, part.category, coins)
+			holdings = append(holdings, collected...)
+			denomErrors = append(denomErrors, errs...)
+		}
+
+		if failed == 3 {
+			failedChains++
+			continue
+		}
+		chainHoldings[chain.name] = holdings
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if failedChains == len(chains) {
+		return nil, classify(ErrUpstreamUnavailable, fmt.Errorf("%s | failed to get balance from all lcd chains: %w", accountAddress, chainErrors[len(chainErrors)-1]))
+	}
+
+	prices := getCosmosPrices(chainHoldings, priceSource)
+
+	totalBalance := new(big.Float)
+	chainPools := make([]customTypes.ChainPools, 0, len(chainHoldings))
+	for _, chain := range chains {
+		holdings := chainHoldings[chain.name]
+		if len(holdings) == 0 {
+			continue
+		}
+
+		var protocols []customTypes.ProtocolPools
+		for _, category := range []string{cosmosPoolLiquid, cosmosPoolStaked, cosmosPoolRewards} {
+			var pools []customTypes.PoolData
+			for _, holding := range holdings {
+				if holding.category != category {
+					continue
+				}
+
+				balanceUSD := new(big.Float)
+				if price, found := prices[holding.priceKey]; found && !holding.unpriced {
+					balanceUSD.Mul(holding.amount, big.NewFloat(price.USD))
+					totalBalance.Add(totalBalance, balanceUSD)
+				}
+				pools = append(pools, customTypes.PoolData{Name: holding.symbol, BalanceUSD: balanceUSD, Amount: holding.amount})
+			}
+			if len(pools) > 0 {
+				protocols = append(protocols, customTypes.ProtocolPools{ProtocolName: category, Pools: pools})
+			}
+		}
+
+		chainPools = append(chainPools, customTypes.ChainPools{ChainName: chain.name, Protocols: protocols})
+	}
+
+	total, _ := totalBalance.Float64()
+	response := newServerResponse(accountAddress, accountData, total)
+	setPools(response, chainPools)
+	addResponseErrors(response, chainErrors...)
+	addResponseErrors(response, denomErrors...)
+
+	log.Printf("%s | Balances Found On %d Cosmos Chains", accountAddress, len(chainPools))
+	return response, nil
+}
+
+func getCosmosPrices(chainHoldings map[string][]cosmosHolding, priceSource PriceSource) map[customTypes.PriceKey]customTypes.PriceInfo {
+	if priceSource == nil {
+		return nil
+	}
+
+	var keys []customTypes.PriceKey
+	seen := make(map[customTypes.PriceKey]bool)
+	for _, holdings := range chainHoldings {
+		for _, holding := range holdings {
+			if !holding.unpriced && !seen[holding.priceKey] {
+				seen[holding.priceKey] = true
+				keys = append(keys, holding.priceKey)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prices, err := priceSource.GetPrices(keys)
+	if err != nil {
+		log.Printf("Failed To Apply Prices: %v", err)
+	}
+	return prices
+}
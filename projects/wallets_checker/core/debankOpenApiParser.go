@@ -0,0 +1,304 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+const defaultDebankOpenApiURL = "https://pro-openapi.debank.com"
+
+type debankOpenApiProvider struct{}
+
+func init() {
+	RegisterProvider(debankOpenApiProvider{})
+}
+
+func (debankOpenApiProvider) Name() string {
+	return "debank_openapi"
+}
+
+func (debankOpenApiProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Tokens: true, Pools: true, Families: evmFamilies}
+}
+
+func (debankOpenApiProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	return ParseDebankOpenApiAccount(ctx, accountData, opts.Proxies, opts.Config)
+}
+
+func (debankOpenApiProvider) UnitsBalance(ctx context.Context, opts CheckOptions) (float64, error) {
+	return getOpenApiUnitsBalance(ctx, GetRetryPolicy(opts.Config, "debank_openapi"), opts.Config.DebankOpenApiConfig, opts.Proxies)
+}
+
+// doOpenApiRequest выполняет запрос к официальному DeBank Cloud API с заголовком AccessKey.
+func doOpenApiRequest(ctx context.Context, policy RetryPolicy, config customTypes.DebankOpenApiConfig, path string, params url.Values, proxies []string, result interface{}) error {
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = defaultDebankOpenApiURL
+	}
+
+	return policy.Do(ctx, "debank openapi "+path, func() error {
+		client, err := GetClient(proxies)
+		if err != nil {
+			return err
+		}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI(fmt.Sprintf("%s%s?%s", strings.TrimSuffix(baseURL, "/"), path, params.Encode()))
+		req.Header.SetMethod(fasthttp.MethodGet)
+		req.Header.Set("accept", "application/json")
+		req.Header.Set("AccessKey", config.AccessKey)
+
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+
+		if err := doWithContext(ctx, client, req, resp); err != nil {
+			return fmt.Errorf("%s request error: %w", path, err)
+		}
+
+		// Неверный или заблокированный AccessKey
+		if resp.StatusCode() == fasthttp.StatusUnauthorized || resp.StatusCode() == fasthttp.StatusForbidden {
+			return permanent(classify(ErrInvalidCredentials, fmt.Errorf("%s access key rejected: status code %d", path, resp.StatusCode())))
+		}
+
+		if resp.StatusCode() != fasthttp.StatusOK {
+			return statusError(fmt.Errorf("%s unexpected status code %d: %s", path, resp.StatusCode(), resp.Body()),
+				resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
+		}
+
+		if err := json.Unmarshal(resp.Body(), result); err != nil {
+			return permanent(classify(ErrUpstreamSchemaChanged, fmt.Errorf("%s failed to parse JSON response: %v", path, err)))
+		}
+
+		return nil
+	})
+}
+
+// getOpenApiUnitsBalance возвращает остаток units на ключе. Сам запрос units не расходует.
+func getOpenApiUnitsBalance(ctx context.Context, policy RetryPolicy, config customTypes.DebankOpenApiConfig, proxies []string) (float64, error) {
+	var responseData struct {
+		Balance float64 `json:"balance"`
+	}
+
+	if err := doOpenApiRequest(ctx, policy, config, "/v1/account/units", url.Values{}, proxies, &responseData); err != nil {
+		return 0, err
+	}
+	return responseData.Balance, nil
+}
+
+func getOpenApiTotalBalance(ctx context.Context, policy RetryPolicy, config customTypes.DebankOpenApiConfig, accountAddress string, proxies []string) (float64, error) {
+	params := url.Values{}
+	params.Set("id", strings.ToLower(accountAddress))
+
+	var responseData struct {
+		TotalUsdValue float64 `json:"total_usd_value"`
+	}
+
+	if err := doOpenApiRequest(ctx, policy, config, "/v1/user/total_balance", params, proxies, &responseData); err != nil {
+		return 0, err
+	}
+	return responseData.TotalUsdValue, nil
+}
+
+func getOpenApiTokens(ctx context.Context, policy RetryPolicy, config customTypes.DebankOpenApiConfig, accountAddress string, proxies []string) ([]customTypes.ChainTokens, error) {
+	type tokenData struct {
+		ID     string          `json:"id"`
+		Chain  string          `json:"chain"`
+		Name   string          `json:"name"`
+		Symbol string          `json:"symbol"`
+		Price  *CustomBigFloat `json:"price"`
+		Amount CustomBigFloat  `json:"amount"`
+	}
+
+	params := url.Values{}
+	params.Set("id", strings.ToLower(accountAddress))
+	params.Set("is_all", "false")
+
+	var responseData []tokenData
+	if err := doOpenApiRequest(ctx, policy, config, "/v1/user/all_token_list", params, proxies, &responseData); err != nil {
+		return nil, err
+	}
+
+	tokensByChain := make(map[string][]customTypes.TokenData)
+	var chainOrder []string
+
+	for _, token := range responseData {
+		if token.Amount.Float == nil {
+			continue
+		}
+
+		tokenInUsd := new(big.Float)
+		if token.Price != nil && token.Price.Float != nil {
+			tokenInUsd.Mul(token.Price.Float, token.Amount.Float)
+		}
+
+		name := token.Symbol
+		if name == "" {
+			name = token.Name
+		}
+
+		if _, exists := tokensByChain[token.Chain]; !exists {
+			chainOrder = append(chainOrder, token.Chain)
+		}
+		tokensByChain[token.Chain] = append(tokensByChain[token.Chain], customTypes.TokenData{
+			Name:            name,
+			BalanceUSD:      tokenInUsd,
+			Amount:          token.Amount.Float,
+			ContractAddress: token.ID,
+		})
+	}
+
+	chainTokens := make([]customTypes.ChainTokens, 0, len(chainOrder))
+	for _, chainName := range chainOrder {
+		tokens := tokensByChain[chainName]
+		SortByBalanceUSD(tokens, func(token customTypes.TokenData) *big.Float { return token.BalanceUSD })
+		chainTokens = append(chainTokens, customTypes.ChainTokens{ChainName: chainName, Tokens: tokens})
+	}
+
+	return chainTokens, nil
+}
+
+func getOpenApiPools(ctx context.Context, policy RetryPolicy, config customTypes.DebankOpenApiConfig, accountAddress string, proxies []string) ([]customTypes.ChainPools, error) {
+	type assetToken struct {
+		Symbol string          `json:"symbol"`
+		Name   string          `json:"name"`
+		Price  *CustomBigFloat `json:"price"`
+		Amount CustomBigFloat  `json:"amount"`
+	}
+
+	type protocolData struct {
+		Chain             string `json:"chain"`
+		Name              string `json:"name"`
+		PortfolioItemList []struct {
+			AssetTokenList []assetToken `json:"asset_token_list"`
+			Detail         struct {
+				SupplyTokenList []assetToken `json:"supply_token_list"`
+				RewardTokenList []assetToken `json:"reward_token_list"`
+			} `json:"detail"`
+		} `json:"portfolio_item_list"`
+	}
+
+	params := url.Values{}
+	params.Set("id", strings.ToLower(accountAddress))
+
+	var responseData []protocolData
+	if err := doOpenApiRequest(ctx, policy, config, "/v1/user/all_complex_protocol_list", params, proxies, &responseData); err != nil {
+		return nil, err
+	}
+
+	chainPools := make([]customTypes.ChainPools, 0)
+	chainIndexes := make(map[string]int)
+
+	for _, protocol := range responseData {
+		protocolPools := customTypes.ProtocolPools{
+			ProtocolName: protocol.Name,
+			Pools:        make([]customTypes.PoolData, 0),
+		}
+
+		for _, item := range protocol.PortfolioItemList {
+			// В старых ответах asset_token_list отсутствует, тогда собираем его из detail
+			tokens := item.AssetTokenList
+			if len(tokens) == 0 {
+				tokens = append(append(tokens, item.Detail.SupplyTokenList...), item.Detail.RewardTokenList...)
+			}
+
+			for _, token := range tokens {
+				if token.Amount.Float == nil {
+					continue
+				}
+
+				tokenInUsd := new(big.Float)
+				if token.Price != nil && token.Price.Float != nil {
+					tokenInUsd.Mul(token.Price.Float, token.Amount.Float)
+				}
+
+				name := token.Symbol
+				if name == "" {
+					name = token.Name
+				}
+
+				protocolPools.Pools = append(protocolPools.Pools, customTypes.PoolData{
+					Name:       name,
+					BalanceUSD: tokenInUsd,
+					Amount:     token.Amount.Float,
+				})
+			}
+		}
+
+		if len(protocolPools.Pools) == 0 {
+			continue
+		}
+
+		index, exists := chainIndexes[protocol.Chain]
+		if !exists {
+			index = len(chainPools)
+			chainIndexes[protocol.Chain] = index
+			chainPools = append(chainPools, customTypes.ChainPools{
+				ChainName: protocol.Chain,
+				Protocols: make([]customTypes.ProtocolPools, 0),
+			})
+		}
+		chainPools[index].Protocols = append(chainPools[index].Protocols, protocolPools)
+	}
+
+	return chainPools, nil
+}
+
+// ParseDebankOpenApiAccount собирает данные через документированный pro-openapi DeBank Cloud.
+// Флаги parse_tokens / parse_pools берутся из debank_config.
+func ParseDebankOpenApiAccount(ctx context.Context, accountData string, proxies []string, config customTypes.ConfigStruct) (*customTypes.ServerResponse, error) {
+	accountAddress, err := getAccountAddress(accountData, evmFamilies)
+	if err != nil {
+		return nil, err
+	}
+
+	openApiConfig := config.DebankOpenApiConfig
+	policy := GetRetryPolicy(config, "debank_openapi")
+	if openApiConfig.AccessKey == "" {
+		return nil, classify(ErrInvalidCredentials, fmt.Errorf("debank openapi access key is not set"))
+	}
+
+	totalUsdBalance, err := getOpenApiTotalBalance(ctx, policy, openApiConfig, accountAddress, proxies)
+	if err != nil {
+		return nil, fmt.Errorf("%s | %w", accountAddress, err)
+	}
+	log.Printf("%s | Total USD Balance: %f $", accountAddress, totalUsdBalance)
+
+	response := newServerResponse(accountAddress, accountData, totalUsdBalance)
+
+	if config.DebankConfig.ParseTokens {
+		chainTokens, err := getOpenApiTokens(ctx, policy, openApiConfig, accountAddress, proxies)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		// Общий баланс уже получен и оплачен units, поэтому ошибка списка токенов не отменяет результат
+		if err != nil {
+			addResponseErrors(response, err)
+		} else {
+			setTokens(response, chainTokens)
+		}
+	}
+
+	if config.DebankConfig.ParsePools {
+		chainPools, err := getOpenApiPools(ctx, policy, openApiConfig, accountAddress, proxies)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			addResponseErrors(response, err)
+		} else {
+			setPools(response, chainPools)
+			log.Printf("%s | Successfully Parsed Pools", accountAddress)
+		}
+	}
+
+	return response, nil
+}
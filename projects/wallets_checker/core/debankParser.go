@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"debank_checker_v3/customTypes"
 	"debank_checker_v3/utils"
 	"encoding/json"
@@ -35,13 +36,17 @@ func (c *CustomBigFloat) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
-func doRequest(accountAddress string,
+func doRequest(ctx context.Context,
+	accountAddress string,
 	baseURL string,
 	method string,
 	path string,
 	params url.Values,
 	payload map[string]interface{}, proxies []string) ([]byte, error) {
-	client := GetClient(proxies)
+	client, err := GetClient(proxies)
+	if err != nil {
+		return nil, fmt.Errorf("%s | %w", accountAddress, err)
+	}
 
 	err, requestParams := utils.GenerateSignature(payload, strings.ToUpper(method), path)
 
@@ -67,12 +72,18 @@ func doRequest(accountAddress string,
 	resp := fasthttp.AcquireResponse()
 	defer fasthttp.ReleaseResponse(resp)
 
-	if err = client.Do(req, resp); err != nil {
-		return nil, fmt.Errorf("%s | Request error: %v", accountAddress, err)
+	if err = doWithContext(ctx, client, req, resp); err != nil {
+		return nil, fmt.Errorf("%s | Request error: %w", accountAddress, err)
 	}
 
 	if resp.StatusCode() == 429 {
-		return nil, fmt.Errorf("%s | Rate limit", accountAddress)
+		return nil, statusError(fmt.Errorf("%s | Rate limit", accountAddress), resp.StatusCode(),
+			string(resp.Header.Peek("Retry-After")))
+	}
+
+	if resp.StatusCode() >= 400 {
+		return nil, statusError(fmt.Errorf("%s | Unexpected status code: %d", accountAddress, resp.StatusCode()),
+			resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
 	}
 
 	respBody := make([]byte, len(resp.Body()))
@@ -80,7 +91,7 @@ func doRequest(accountAddress string,
 	return respBody, nil
 }
 
-func getTotalUsdBalance(accountAddress string, proxies []string) float64 {
+func getTotalUsdBalance(ctx context.Context, policy RetryPolicy, accountAddress string, proxies []string) (float64, error) {
 	baseURL := "https://api.debank.com/asset/net_curve_24h"
 	path := "/asset/net_curve_24h"
 	params := url.Values{}
@@ -90,12 +101,13 @@ func getTotalUsdBalance(accountAddress string, proxies []string) float64 {
 		"user_addr": strings.ToLower(accountAddress),
 	}
 
-	for {
-		respBody, err := doRequest(accountAddress, baseURL, "GET", path, params, payload, proxies)
+	var totalUsdBalance float64
+
+	err := policy.Do(ctx, accountAddress+" | "+path, func() error {
+		respBody, err := doRequest(ctx, accountAddress, baseURL, "GET", path, params, payload, proxies)
 
 		if err != nil {
-			log.Printf("%s", err)
-			continue
+			return err
 		}
 
 		var responseData struct {
@@ -105,29 +117,29 @@ func getTotalUsdBalance(accountAddress string, proxies []string) float64 {
 		}
 
 		if err = json.Unmarshal(respBody, &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
 		}
 
 		usdValueList := responseData.Data.UsdValueList
 
 		if len(usdValueList) < 1 {
-			log.Printf("%s | UsdValueList is Empty", accountAddress)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("usd_value_list is empty"))
 		}
 
 		lastEntry := usdValueList[len(usdValueList)-1]
 
 		if len(lastEntry) < 2 {
-			log.Printf("%s | Last Entry Does Not Contain Enough Elements", accountAddress)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("last entry does not contain enough elements"))
 		}
 
-		return lastEntry[1]
-	}
+		totalUsdBalance = lastEntry[1]
+		return nil
+	})
+
+	return totalUsdBalance, err
 }
 
-func getUsedChains(accountAddress string, path string, proxies []string) []string {
+func getUsedChains(ctx context.Context, policy RetryPolicy, accountAddress string, path string, proxies []string) ([]string, error) {
 	baseURL := "https://api.debank.com" + path
 	var payload map[string]interface{}
 	var responseData interface{}
@@ -156,42 +168,45 @@ func getUsedChains(accountAddress string, path string, proxies []string) []strin
 			} `json:"data"`
 		}{}
 	} else {
-		log.Printf("%s | Wrong Path: %s", accountAddress, path)
-		return nil
-	}	
+		return nil, fmt.Errorf("%s | wrong path: %s", accountAddress, path)
+	}
+
+	var chains []string
 
-	for {
-		respBody, err := doRequest(accountAddress, baseURL, "GET", path, params, payload, proxies)
+	err := policy.Do(ctx, accountAddress+" | "+path, func() error {
+		respBody, err := doRequest(ctx, accountAddress, baseURL, "GET", path, params, payload, proxies)
 
 		if err != nil {
-			log.Printf("%s", err)
-			continue
+			return err
 		}
 
 		if err = json.Unmarshal(respBody, &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
 		}
 
 		switch v := responseData.(type) {
 		case *struct {
 			Data []string `json:"data"`
 		}:
-			return v.Data
+			chains = v.Data
 		case *struct {
 			Data struct {
 				Chains []string `json:"chains"`
 			} `json:"data"`
 		}:
-			return v.Data.Chains
+			chains = v.Data.Chains
 		default:
-			log.Printf("%s | Unexpected Response Format", accountAddress)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("unexpected response format"))
 		}
-	}
+		return nil
+	})
+
+	return chains, err
 }
 
-func getTokenBalances(accountAddress string, chains []string, proxies []string) map[string][]customTypes.TokenBalancesResultData {
+// getTokenBalances возвращает балансы по сетям. Сеть, для которой закончились попытки,
+// пропускается, а ее ошибка добавляется в возвращаемый список.
+func getTokenBalances(ctx context.Context, policy RetryPolicy, accountAddress string, chains []string, proxies []string) (map[string][]customTypes.TokenBalancesResultData, []error) {
 	type tokenData struct {
 		Amount          CustomBigFloat  `json:"amount"`
 		Balance         big.Int         `json:"balance"`
@@ -212,27 +227,27 @@ func getTokenBalances(accountAddress string, chains []string, proxies []string)
 	params.Set("user_addr", strings.ToLower(accountAddress))
 
 	result := make(map[string][]customTypes.TokenBalancesResultData)
+	var errs []error
 
 	for _, currentChain := range chains {
-		for {
+		params.Set("chain", currentChain)
+		payload := map[string]interface{}{
+			"user_addr": strings.ToLower(accountAddress),
+			"chain":     currentChain,
+		}
+
+		err := policy.Do(ctx, accountAddress+" | "+path+" | "+currentChain, func() error {
 			responseData := &responseStruct{}
 			var tokensResultData []customTypes.TokenBalancesResultData
-			params.Set("chain", currentChain)
-			payload := map[string]interface{}{
-				"user_addr": strings.ToLower(accountAddress),
-				"chain":     currentChain,
-			}
 
-			respBody, err := doRequest(accountAddress, baseURL, "GET", path, params, payload, proxies)
+			respBody, err := doRequest(ctx, accountAddress, baseURL, "GET", path, params, payload, proxies)
 
 			if err != nil {
-				log.Printf("%s", err)
-				continue
+				return err
 			}
 
 			if err = json.Unmarshal(respBody, responseData); err != nil {
-				log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-				continue
+				return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
 			}
 
 			for _, currentToken := range responseData.Data {
@@ -251,14 +266,21 @@ func getTokenBalances(accountAddress string, chains []string, proxies []string)
 			}
 
 			result[currentChain] = tokensResultData
-			break
+			return nil
+		})
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, append(errs, err)
+			}
+			errs = append(errs, err)
 		}
 	}
 
-	return result
+	return result, errs
 }
 
-func getPoolBalances(accountAddress string, proxies []string) map[string]map[string][]customTypes.PoolBalancesResultData {
+func getPoolBalances(ctx context.Context, policy RetryPolicy, accountAddress string, proxies []string) (map[string]map[string][]customTypes.PoolBalancesResultData, error) {
 	type assetToken struct {
 		Amount CustomBigFloat  `json:"amount"`
 		Name   string          `json:"name"`
@@ -290,19 +312,17 @@ func getPoolBalances(accountAddress string, proxies []string) map[string]map[str
 
 	result := make(map[string]map[string][]customTypes.PoolBalancesResultData)
 
-	for {
-		respBody, err := doRequest(accountAddress, baseURL, "GET", path, params, payload, proxies)
+	err := policy.Do(ctx, accountAddress+" | "+path, func() error {
+		respBody, err := doRequest(ctx, accountAddress, baseURL, "GET", path, params, payload, proxies)
 
 		if err != nil {
-			log.Printf("%s", err)
-			continue
+			return err
 		}
 
 		responseData := &responseStruct{}
 
 		if err = json.Unmarshal(respBody, responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
 		}
 
 		for _, currentPool := range responseData.Data {
@@ -331,13 +351,20 @@ func getPoolBalances(accountAddress string, proxies []string) map[string]map[str
 			}
 		}
 
-		break
-	}
+		return nil
+	})
 
-	return result
+	return result, err
 }
 
-func getNftBalances(accountAddress string, chains []string, proxies []string) map[string][]customTypes.NftBalancesResultData {
+// Пока DeBank собирает NFT, ответ приходит со статусом job "pending". Ожидание не считается
+// неудачной попыткой RetryPolicy: у него свой срок, индексация NFT бывает долгой.
+const (
+	nftPendingDelay   = 3 * time.Second
+	nftPendingTimeout = 3 * time.Minute
+)
+
+func getNftBalances(ctx context.Context, policy RetryPolicy, accountAddress string, chains []string, proxies []string) (map[string][]customTypes.NftBalancesResultData, []error) {
 	type collectionData struct {
 		Amount          CustomBigFloat `json:"amount"`
 		AvgPriceLast24h CustomBigFloat `json:"avg_price_last_24h"`
@@ -365,61 +392,82 @@ func getNftBalances(accountAddress string, chains []string, proxies []string) ma
 	baseURL := "https://api.debank.com/nft/collection_list"
 	path := "/nft/collection_list"
 	result := make(map[string][]customTypes.NftBalancesResultData)
+	var errs []error
 
 	params := url.Values{}
 	params.Set("user_addr", strings.ToLower(accountAddress))
 
 	for _, currentChain := range chains {
+		params.Set("chain", currentChain)
+		payload := map[string]interface{}{
+			"user_addr": strings.ToLower(accountAddress),
+			"chain":     currentChain,
+		}
+
+		pendingDeadline := time.Now().Add(nftPendingTimeout)
+		var err error
+
 		for {
-			params.Set("chain", currentChain)
-			payload := map[string]interface{}{
-				"user_addr": strings.ToLower(accountAddress),
-				"chain":     currentChain,
-			}
+			pending := false
+			err = policy.Do(ctx, accountAddress+" | "+path+" | "+currentChain, func() error {
+				respBody, err := doRequest(ctx, accountAddress, baseURL, "GET", path, params, payload, proxies)
 
-			respBody, err := doRequest(accountAddress, baseURL, "GET", path, params, payload, proxies)
+				if err != nil {
+					return err
+				}
 
-			if err != nil {
-				log.Printf("%s", err)
-				continue
-			}
+				responseData := &responseStruct{}
 
-			responseData := &responseStruct{}
+				if err = json.Unmarshal(respBody, responseData); err != nil {
+					return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
+				}
 
-			if err = json.Unmarshal(respBody, responseData); err != nil {
-				log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-				continue
-			}
+				if responseData.Data.Job != nil && responseData.Data.Job.Status == "pending" {
+					pending = true
+					return nil
+				}
 
-			if responseData.Data.Job != nil && responseData.Data.Job.Status == "pending" {
-				log.Printf("%s | NFT Balance Pending, sleeping 3 secs...", accountAddress)
-				time.Sleep(3 * time.Second)
-				continue
-			}
+				for _, currentNftData := range responseData.Data.Result.Data {
+					var nftInUsd *big.Float
 
-			for _, currentNftData := range responseData.Data.Result.Data {
-				var nftInUsd *big.Float
+					if currentNftData.SpentToken.Price != nil {
+						nftInUsd = new(big.Float).Mul(new(big.Float).Mul(currentNftData.AvgPriceLast24h.Float, currentNftData.SpentToken.Price.Float), currentNftData.Amount.Float)
+					} else {
+						nftInUsd = new(big.Float)
+					}
 
-				if currentNftData.SpentToken.Price != nil {
-					nftInUsd = new(big.Float).Mul(new(big.Float).Mul(currentNftData.AvgPriceLast24h.Float, currentNftData.SpentToken.Price.Float), currentNftData.Amount.Float)
-				} else {
-					nftInUsd = new(big.Float)
+					result[currentChain] = append(result[currentChain],
+						customTypes.NftBalancesResultData{
+							Name:       currentNftData.Name,
+							Amount:     currentNftData.Amount.Float,
+							BalanceUSD: nftInUsd,
+						})
 				}
+				return nil
+			})
 
-				amountBigInt := new(big.Int)
-				currentNftData.Amount.Float.Int(amountBigInt)
-				result[currentChain] = append(result[currentChain],
-					customTypes.NftBalancesResultData{
-						Name:       currentNftData.Name,
-						Amount:     currentNftData.Amount.Float,
-						BalanceUSD: nftInUsd,
-					})
+			if err != nil || !pending {
+				break
+			}
+			if time.Now().After(pendingDeadline) {
+				err = classify(ErrUpstreamUnavailable, fmt.Errorf("%s | nft balance still pending after %s", currentChain, nftPendingTimeout))
+				break
 			}
-			break
+			log.Printf("%s | NFT Balance Pending, sleeping %s...", accountAddress, nftPendingDelay)
+			if err = sleepContext(ctx, nftPendingDelay); err != nil {
+				break
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, append(errs, err)
+			}
+			errs = append(errs, err)
 		}
 	}
 
-	return result
+	return result, errs
 }
 
 func SortByBalanceUSD[T any](slice []T, balanceUSDGetter func(T) *big.Float) {
@@ -442,29 +490,50 @@ func SortNestedMapByBalanceUSD[T any](data map[string]map[string][]T, getBalance
 	}
 }
 
-func ParseDebankAccount(accountData string, proxies []string) (*customTypes.ServerResponse, error) {
-	accountAddress, err := utils.GetAccountAddress(accountData)
+type debankProvider struct{}
+
+func init() {
+	RegisterProvider(debankProvider{})
+}
+
+func (debankProvider) Name() string {
+	return "debank"
+}
+
+func (debankProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Tokens: true, NFTs: true, Pools: true, Families: evmFamilies}
+}
+
+func (debankProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	return ParseDebankAccount(ctx, accountData, opts.Proxies, opts.Config)
+}
+
+func ParseDebankAccount(ctx context.Context, accountData string, proxies []string, config customTypes.ConfigStruct) (*customTypes.ServerResponse, error) {
+	accountAddress, err := getAccountAddress(accountData, evmFamilies)
 	if err != nil {
 		return nil, err
 	}
 
-	totalUsdBalance := getTotalUsdBalance(accountAddress, proxies)
-	log.Printf("%s | Total USD Balance: %f $", accountAddress, totalUsdBalance)
+	policy := GetRetryPolicy(config, "debank")
 
-	response := &customTypes.ServerResponse{
-		WalletAddress: accountAddress,
-		WalletData:    accountData,
-		TotalBalance:  totalUsdBalance,
+	totalUsdBalance, err := getTotalUsdBalance(ctx, policy, accountAddress, proxies)
+	if err != nil {
+		return nil, err
 	}
+	log.Printf("%s | Total USD Balance: %f $", accountAddress, totalUsdBalance)
 
-	if utils.ConfigFile.DebankConfig.ParseTokens {
-		tokenChainsUsed := getUsedChains(accountAddress, "/user/used_chains", proxies)
+	response := newServerResponse(accountAddress, accountData, totalUsdBalance)
+
+	if config.DebankConfig.ParseTokens {
+		tokenChainsUsed, err := getUsedChains(ctx, policy, accountAddress, "/user/used_chains", proxies)
+		if err != nil {
+			addResponseErrors(response, err)
+		}
 		log.Printf("%s | Token Chains Used: %d", accountAddress, len(tokenChainsUsed))
 
 		if len(tokenChainsUsed) > 0 {
-			tokenBalances := getTokenBalances(accountAddress, tokenChainsUsed, proxies)
-			
-			totalTokens := 0
+			tokenBalances, errs := getTokenBalances(ctx, policy, accountAddress, tokenChainsUsed, proxies)
+			addResponseErrors(response, errs...)
 			chainTokens := make([]customTypes.ChainTokens, 0)
 
 			for chainName, tokens := range tokenBalances {
@@ -480,24 +549,28 @@ func ParseDebankAccount(accountData string, proxies []string) (*customTypes.Serv
 						Amount:          token.Amount,
 						ContractAddress: token.ContractAddress,
 					})
-					totalTokens++
 				}
 
 				chainTokens = append(chainTokens, chainData)
 			}
-			response.Tokens.Quantity = totalTokens
-			response.Tokens.Data = chainTokens
+			setTokens(response, chainTokens)
+		}
+
+		if err = ctx.Err(); err != nil {
+			return nil, err
 		}
 	}
 
-	if utils.ConfigFile.DebankConfig.ParseNfts {
-		nftChainsUsed := getUsedChains(accountAddress, "/nft/used_chains", proxies)
+	if config.DebankConfig.ParseNfts {
+		nftChainsUsed, err := getUsedChains(ctx, policy, accountAddress, "/nft/used_chains", proxies)
+		if err != nil {
+			addResponseErrors(response, err)
+		}
 		log.Printf("%s | NFT Chains Used: %d", accountAddress, len(nftChainsUsed))
 
 		if len(nftChainsUsed) > 0 {
-			nftBalances := getNftBalances(accountAddress, nftChainsUsed, proxies)
-			
-			totalNfts := 0
+			nftBalances, errs := getNftBalances(ctx, policy, accountAddress, nftChainsUsed, proxies)
+			addResponseErrors(response, errs...)
 			chainNfts := make([]customTypes.ChainNfts, 0)
 
 			for chainName, nfts := range nftBalances {
@@ -512,21 +585,29 @@ func ParseDebankAccount(accountData string, proxies []string) (*customTypes.Serv
 						PriceUSD: nft.BalanceUSD,
 						Amount:   nft.Amount,
 					})
-					totalNfts++
 				}
 
 				chainNfts = append(chainNfts, chainData)
 			}
-			response.NFTs.Quantity = totalNfts
-			response.NFTs.Data = chainNfts
+			setNfts(response, chainNfts)
+		}
+
+		if err = ctx.Err(); err != nil {
+			return nil, err
 		}
 	}
 
-	if utils.ConfigFile.DebankConfig.ParsePools {
-		poolsData := getPoolBalances(accountAddress, proxies)
-		log.Printf("%s | Successfully Parsed Pools", accountAddress)
+	if config.DebankConfig.ParsePools {
+		poolsData, err := getPoolBalances(ctx, policy, accountAddress, proxies)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if err != nil {
+			addResponseErrors(response, err)
+		} else {
+			log.Printf("%s | Successfully Parsed Pools", accountAddress)
+		}
 
-		totalPools := 0
 		chainPools := make([]customTypes.ChainPools, 0)
 
 		for chainName, protocols := range poolsData {
@@ -547,7 +628,6 @@ func ParseDebankAccount(accountData string, proxies []string) (*customTypes.Serv
 						BalanceUSD: pool.BalanceUSD,
 						Amount:     pool.Amount,
 					})
-					totalPools++
 				}
 
 				chainData.Protocols = append(chainData.Protocols, protocolData)
@@ -555,8 +635,7 @@ func ParseDebankAccount(accountData string, proxies []string) (*customTypes.Serv
 
 			chainPools = append(chainPools, chainData)
 		}
-		response.Pools.Quantity = totalPools
-		response.Pools.Data = chainPools
+		setPools(response, chainPools)
 	}
 
 	return response, nil
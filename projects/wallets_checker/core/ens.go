@@ -0,0 +1,255 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	ensRegistryAddress         = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+	ensReverseRegistrarAddress = "0xa58E81fe9b61B5c3fE2AFD33CF304c454AbFc7Cb"
+
+	ensCacheTTL       = 10 * time.Minute
+	ensResolveTimeout = 10 * time.Second
+)
+
+var (
+	// resolver(bytes32)
+	ensResolverSelector = []byte{0x01, 0x78, 0xb8, 0xbf}
+	// addr(bytes32)
+	ensAddrSelector = []byte{0x3b, 0x3b, 0x57, 0xde}
+	// name(bytes32)
+	ensNameSelector = []byte{0x69, 0x1f, 0x34, 0x31}
+	// node(address)
+	ensReverseNodeSelector = []byte{0xbf, 0xfb, 0xe6, 0x1c}
+)
+
+type ensCacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+var (
+	ensMu         sync.Mutex
+	ensCache      = make(map[string]ensCacheEntry)
+	defaultEnsRPC string
+)
+
+// SetEnsRPC задает mainnet RPC для ENS по умолчанию и включает разрешение имен name.eth
+// при импорте и проверке аккаунтов. Пустой url выключает разрешение имен.
+func SetEnsRPC(rpcURL string) {
+	ensMu.Lock()
+	defaultEnsRPC = rpcURL
+	ensMu.Unlock()
+
+	if rpcURL == "" {
+		utils.SetNameResolver(nil)
+		return
+	}
+
+	utils.SetNameResolver(func(name string) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), ensResolveTimeout)
+		defer cancel()
+		return ResolveEnsName(ctx, rpcURL, name)
+	})
+}
+
+func getCachedEns(key string) (string, bool) {
+	ensMu.Lock()
+	defer ensMu.Unlock()
+
+	entry, exists := ensCache[key]
+	if !exists || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.value, true
+}
+
+func setCachedEns(key string, value string) {
+	ensMu.Lock()
+	defer ensMu.Unlock()
+	ensCache[key] = ensCacheEntry{value: value, expiresAt: time.Now().Add(ensCacheTTL)}
+}
+
+// ensNamehash - алгоритм namehash из EIP-137. Нормализация ENSIP-15 сводится к нижнему регистру.
+func ensNamehash(name string) common.Hash {
+	var node common.Hash
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(strings.ToLower(name), ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node.Bytes(), crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+func ensCall(ctx context.Context, policy RetryPolicy, rpcURL string, to string, callData []byte) ([]byte, error) {
+	var result []byte
+	err := policy.Do(ctx, "ens eth_call", func() error {
+		var err error
+		result, err = ethCall(ctx, rpcURL, to, callData)
+		return err
+	})
+	return result, err
+}
+
+func decodeAbiAddress(data []byte) (common.Address, error) {
+	if len(data) != 32 {
+		return common.Address{}, classify(ErrUpstreamSchemaChanged, fmt.Errorf("unexpected address length: %d", len(data)))
+	}
+	return common.BytesToAddress(data), nil
+}
+
+func decodeAbiString(data []byte) (string, error) {
+	if len(data) < 64 {
+		return "", classify(ErrUpstreamSchemaChanged, fmt.Errorf("unexpected string length: %d", len(data)))
+	}
+
+	offset := new(big.Int).SetBytes(data[:32])
+	if !offset.IsUint64() || offset.Uint64()+32 > uint64(len(data)) {
+		return "", classify(ErrUpstreamSchemaChanged, fmt.Errorf("invalid string offset"))
+	}
+	start := offset.Uint64() + 32
+
+	length := new(big.Int).SetBytes(data[start-32 : start])
+	if !length.IsUint64() || start+length.Uint64() > uint64(len(data)) {
+		return "", classify(ErrUpstreamSchemaChanged, fmt.Errorf("invalid string length"))
+	}
+	return string(data[start : start+length.Uint64()]), nil
+}
+
+// getEnsResolver возвращает адрес резолвера узла из реестра ENS (нулевой адрес, если его нет).
+func getEnsResolver(ctx context.Context, policy RetryPolicy, rpcURL string, node common.Hash) (common.Address, error) {
+	data, err := ensCall(ctx, policy, rpcURL, ensRegistryAddress, append(append([]byte{}, ensResolverSelector...), node.Bytes()...))
+	if err != nil {
+		return common.Address{}, err
+	}
+	return decodeAbiAddress(data)
+}
+
+// ResolveEnsName возвращает адрес, на который указывает ENS имя.
+func ResolveEnsName(ctx context.Context, rpcURL string, name string) (string, error) {
+	name = strings.ToLower(name)
+	cacheKey := "addr|" + rpcURL + "|" + name
+	if address, found := getCachedEns(cacheKey); found {
+		return address, nil
+	}
+
+	policy := GetRetryPolicy(customTypes.ConfigStruct{}, "ens")
+	node := ensNamehash(name)
+
+	resolver, err := getEnsResolver(ctx, policy, rpcURL, node)
+	if err != nil {
+		return "", err
+	}
+	if resolver == (common.Address{}) {
+		return "", fmt.Errorf("%s has no resolver", name)
+	}
+
+	data, err := ensCall(ctx, policy, rpcURL, resolver.Hex(), append(append([]byte{}, ensAddrSelector...), node.Bytes()...))
+	if err != nil {
+		return "", err
+	}
+	address, err := decodeAbiAddress(data)
+	if err != nil {
+		return "", err
+	}
+	if address == (common.Address{}) {
+		return "", fmt.Errorf("%s does not point to an address", name)
+	}
+
+	setCachedEns(cacheKey, address.Hex())
+	return address.Hex(), nil
+}
+
+// LookupEnsName возвращает основное ENS имя адреса через reverse registrar и резолвер обратной записи.
+// Имя возвращается, только если оно указывает обратно на этот же адрес, иначе - пустая строка.
+func LookupEnsName(ctx context.Context, rpcURL string, accountAddress string) (string, error) {
+	address := common.HexToAddress(accountAddress)
+	cacheKey := "name|" + rpcURL + "|" + address.Hex()
+	if name, found := getCachedEns(cacheKey); found {
+		return name, nil
+	}
+
+	policy := GetRetryPolicy(customTypes.ConfigStruct{}, "ens")
+
+	nodeData, err := ensCall(ctx, policy, rpcURL, ensReverseRegistrarAddress,
+		append(append([]byte{}, ensReverseNodeSelector...), common.LeftPadBytes(address.Bytes(), 32)...))
+	if err != nil {
+		return "", err
+	}
+	if len(nodeData) != 32 {
+		return "", classify(ErrUpstreamSchemaChanged, fmt.Errorf("unexpected reverse node length: %d", len(nodeData)))
+	}
+	node := common.BytesToHash(nodeData)
+
+	resolver, err := getEnsResolver(ctx, policy, rpcURL, node)
+	if err != nil {
+		return "", err
+	}
+
+	name := ""
+	if resolver != (common.Address{}) {
+		data, err := ensCall(ctx, policy, rpcURL, resolver.Hex(), append(append([]byte{}, ensNameSelector...), node.Bytes()...))
+		if err != nil {
+			return "", err
+		}
+		if name, err = decodeAbiString(data); err != nil {
+			return "", err
+		}
+	}
+
+	// Обратную запись может выставить кто угодно, поэтому имя проверяется прямым разрешением
+	if name != "" {
+		forward, err := ResolveEnsName(ctx, rpcURL, name)
+		if err != nil || !strings.EqualFold(forward, address.Hex()) {
+			name = ""
+		}
+	}
+
+	setCachedEns(cacheKey, name)
+	return name, nil
+}
+
+// AttachEnsName добавляет в ответ основное ENS имя EVM адреса. RPC берется из ens_config,
+// иначе используется заданный при запуске. Ошибка разрешения не делает результат неполным.
+func AttachEnsName(ctx context.Context, response *customTypes.ServerResponse, config customTypes.ConfigStruct) {
+	if response == nil || response.ChainFamily != utils.FamilyEVM {
+		return
+	}
+
+	rpcURL := config.EnsConfig.RpcURL
+	if rpcURL == "" {
+		ensMu.Lock()
+		rpcURL = defaultEnsRPC
+		ensMu.Unlock()
+	}
+	if rpcURL == "" {
+		return
+	}
+
+	name, err := LookupEnsName(ctx, rpcURL, response.WalletAddress)
+	if err != nil {
+		log.Printf("%s | Failed To Lookup ENS Name: %v", response.WalletAddress, err)
+		return
+	}
+	response.EnsName = name
+}
+
+// EnrichResponse дополняет результат проверки данными, которые не зависят от провайдера балансов.
+func EnrichResponse(ctx context.Context, response *customTypes.ServerResponse, config customTypes.ConfigStruct) {
+	AttachEnsName(ctx, response, config)
+	AttachActivity(ctx, response, config)
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Категории ошибок проверки. Проверяются через errors.Is, по ним выбираются HTTP статус и code для фронта.
+var (
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrRateLimited           = errors.New("rate limited")
+	ErrUpstreamUnavailable   = errors.New("upstream unavailable")
+	ErrUpstreamSchemaChanged = errors.New("upstream schema changed")
+	ErrPartialResult         = errors.New("partial result")
+	ErrUnsupportedChain      = errors.New("unsupported chain family")
+	// ErrInvalidConfig - ошибка в настройках запроса (прокси, сети провайдера), а не в данных аккаунта
+	ErrInvalidConfig = errors.New("invalid config")
+)
+
+const (
+	CodeInvalidCredentials    = "invalid_credentials"
+	CodeRateLimited           = "rate_limited"
+	CodeUpstreamUnavailable   = "upstream_unavailable"
+	CodeUpstreamSchemaChanged = "upstream_schema_changed"
+	CodePartialResult         = "partial_result"
+	CodeUnsupportedChain      = "unsupported_chain"
+	CodeInvalidConfig         = "invalid_config"
+	CodeCanceled              = "canceled"
+	CodeTimeout               = "timeout"
+	CodeInternal              = "internal_error"
+)
+
+// Error связывает исходную ошибку с ее категорией. Текст ошибки не меняется.
+type Error struct {
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string   { return e.Err.Error() }
+func (e *Error) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+func classify(kind error, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// getAccountAddress - обертка над utils.GetAccountInfo, помечающая ошибку как ErrInvalidCredentials.
+// Аккаунт семейства, которого нет в families, отклоняется с ErrUnsupportedChain.
+func getAccountAddress(accountData string, families []string) (string, error) {
+	info, err := utils.GetAccountInfo(accountData)
+	if err != nil {
+		return "", classify(ErrInvalidCredentials, err)
+	}
+	if !slices.Contains(families, info.Family) {
+		return "", classify(ErrUnsupportedChain, fmt.Errorf("%s account %s is not supported by this provider", info.Family, info.Address))
+	}
+	return info.Address, nil
+}
+
+// PartialResultError возвращает ErrPartialResult, если часть данных ответа получить не удалось.
+func PartialResultError(response *customTypes.ServerResponse) error {
+	if response == nil || len(response.Errors) == 0 {
+		return nil
+	}
+	return classify(ErrPartialResult, fmt.Errorf("%s | %s", response.WalletAddress, strings.Join(response.Errors, "; ")))
+}
+
+// ErrorCode возвращает машиночитаемый код ошибки для JSON ответа.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidCredentials):
+		return CodeInvalidCredentials
+	case errors.Is(err, ErrUnsupportedChain):
+		return CodeUnsupportedChain
+	case errors.Is(err, ErrInvalidConfig):
+		return CodeInvalidConfig
+	case errors.Is(err, ErrRateLimited):
+		return CodeRateLimited
+	case errors.Is(err, ErrUpstreamSchemaChanged):
+		return CodeUpstreamSchemaChanged
+	case errors.Is(err, ErrUpstreamUnavailable):
+		return CodeUpstreamUnavailable
+	case errors.Is(err, ErrPartialResult):
+		return CodePartialResult
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	}
+	return CodeInternal
+}
+
+// HTTPStatus возвращает HTTP статус, соответствующий категории ошибки.
+func HTTPStatus(err error) int {
+	switch ErrorCode(err) {
+	case CodeInvalidCredentials, CodeUnsupportedChain:
+		return http.StatusUnprocessableEntity
+	case CodeInvalidConfig:
+		return http.StatusBadRequest
+	case CodeRateLimited:
+		return http.StatusTooManyRequests
+	case CodeUpstreamSchemaChanged:
+		return http.StatusBadGateway
+	case CodeUpstreamUnavailable:
+		return http.StatusServiceUnavailable
+	case CodePartialResult:
+		// Не 206: это статус ответа на Range запрос. Неполнота видна по полю errors в теле
+		return http.StatusOK
+	case CodeCanceled:
+		// Клиент закрыл соединение, статус уже никто не прочитает
+		return http.StatusRequestTimeout
+	case CodeTimeout:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
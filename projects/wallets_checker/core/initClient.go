@@ -1,22 +1,30 @@
 package core
 
 import (
+	"context"
 	"debank_checker_v3/utils"
+	"fmt"
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/fasthttpproxy"
-	"log"
 	"net/url"
 	"time"
 )
 
-func GetClient(proxies []string) *fasthttp.Client {
+// GetClient создает клиент через случайный прокси из списка. Прокси приводится к виду scheme://host:port
+// через utils.ParseProxy; неверный прокси - ошибка настройки запроса, повторять ее бесполезно.
+// Текст ошибки не содержит прокси: в нем могут быть логин и пароль.
+func GetClient(proxies []string) (*fasthttp.Client, error) {
 	var dial fasthttp.DialFunc
 	randomProxy := utils.GetProxy(proxies)
 
 	if randomProxy != "" {
-		proxy, err := url.Parse(randomProxy)
+		normalized, err := utils.ParseProxy(randomProxy)
 		if err != nil {
-			log.Panicf("Error Unparsing Proxy: %v\n", err)
+			return nil, permanent(classify(ErrInvalidConfig, fmt.Errorf("invalid proxy format")))
+		}
+		proxy, err := url.Parse(normalized)
+		if err != nil {
+			return nil, permanent(classify(ErrInvalidConfig, fmt.Errorf("invalid proxy format")))
 		}
 
 		switch proxy.Scheme {
@@ -27,7 +35,7 @@ func GetClient(proxies []string) *fasthttp.Client {
 		case "socks5":
 			dial = fasthttpproxy.FasthttpSocksDialer(proxy.String())
 		default:
-			log.Panicf("Unsupported proxy scheme: %s\n", proxy.Scheme)
+			return nil, permanent(classify(ErrInvalidConfig, fmt.Errorf("unsupported proxy scheme: %s", proxy.Scheme)))
 		}
 	}
 
@@ -43,5 +51,26 @@ func GetClient(proxies []string) *fasthttp.Client {
 		StreamResponseBody:            true,
 	}
 
-	return client
+	return client, nil
+}
+
+// doWithContext выполняет запрос с учетом ctx: отмененный контекст не дает начать запрос,
+// а дедлайн контекста становится дедлайном запроса. Уже отправленный запрос без дедлайна
+// завершится не позже таймаутов клиента, после чего вернется ошибка контекста.
+func doWithContext(ctx context.Context, client *fasthttp.Client, req *fasthttp.Request, resp *fasthttp.Response) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var err error
+	if deadline, ok := ctx.Deadline(); ok {
+		err = client.DoDeadline(req, resp, deadline)
+	} else {
+		err = client.Do(req, resp)
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return classify(ErrUpstreamUnavailable, err)
 }
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+const rpcLimitExceededCode = -32005
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+type rpcResponse struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// callRpc выполняет один JSON-RPC вызов и декодирует result в result.
+// Работает с любым JSON-RPC 2.0 эндпоинтом (свои ноды, публичные RPC, локальные заглушки).
+func callRpc(ctx context.Context, rpcURL string, method string, params []interface{}, result interface{}) error {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal rpc request: %v", err)
+	}
+
+	client, err := GetClient(nil)
+	if err != nil {
+		return err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(rpcURL)
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.SetContentType("application/json")
+	req.SetBody(body)
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err = doWithContext(ctx, client, req, resp); err != nil {
+		return fmt.Errorf("%s request error: %w", method, err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return statusError(fmt.Errorf("%s unexpected status code: %d", method, resp.StatusCode()),
+			resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
+	}
+
+	var responseData rpcResponse
+	if err = json.Unmarshal(resp.Body(), &responseData); err != nil {
+		return classify(ErrUpstreamSchemaChanged, fmt.Errorf("%s failed to parse rpc response: %v", method, err))
+	}
+
+	if responseData.Error != nil {
+		// -32005 - превышен лимит запросов, остальные ошибки ноды повторять бесполезно
+		if responseData.Error.Code == rpcLimitExceededCode {
+			return classify(ErrRateLimited, fmt.Errorf("%s %w", method, responseData.Error))
+		}
+		return permanent(fmt.Errorf("%s %w", method, responseData.Error))
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err = json.Unmarshal(responseData.Result, result); err != nil {
+		return permanent(classify(ErrUpstreamSchemaChanged, fmt.Errorf("%s failed to parse rpc result: %v", method, err)))
+	}
+
+	return nil
+}
+
+func parseHexBigInt(value string) (*big.Int, error) {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	if value == "" {
+		return new(big.Int), nil
+	}
+
+	result, ok := new(big.Int).SetString(value, 16)
+	if !ok {
+		return nil, fmt.Errorf("invalid hex number: %s", value)
+	}
+	return result, nil
+}
+
+// scaleByDecimals переводит целое значение в минимальных единицах в человекочитаемое количество.
+func scaleByDecimals(value *big.Int, decimals int) *big.Float {
+	amount := new(big.Float).SetInt(value)
+	if decimals <= 0 {
+		return amount
+	}
+
+	divisor := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return amount.Quo(amount, divisor)
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Multicall3 задеплоен по одному и тому же адресу почти во всех EVM сетях.
+const defaultMulticall3Address = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+const defaultMulticallBatchSize = 500
+
+const multicall3ABIJson = `[{"inputs":[{"components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}],"name":"calls","type":"tuple[]"}],"name":"aggregate3","outputs":[{"components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}],"name":"returnData","type":"tuple[]"}],"stateMutability":"payable","type":"function"}]`
+
+var multicall3ABI = mustParseABI(multicall3ABIJson)
+
+// balanceOf(address)
+var erc20BalanceOfSelector = []byte{0x70, 0xa0, 0x82, 0x31}
+
+// getEthBalance(address) - метод самого Multicall3, отдает нативный баланс внутри того же aggregate3
+var multicallGetEthBalanceSelector = []byte{0x4d, 0x23, 0x01, 0xcc}
+
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+func mustParseABI(definition string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(definition))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func encodeAggregate3(calls []multicallCall) ([]byte, error) {
+	return multicall3ABI.Pack("aggregate3", calls)
+}
+
+func decodeAggregate3(data []byte) ([]multicallResult, error) {
+	unpacked, err := multicall3ABI.Unpack("aggregate3", data)
+	if err != nil {
+		return nil, classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to decode aggregate3 response: %v", err))
+	}
+	if len(unpacked) != 1 {
+		return nil, fmt.Errorf("unexpected aggregate3 output length: %d", len(unpacked))
+	}
+
+	results := *abi.ConvertType(unpacked[0], new([]multicallResult)).(*[]multicallResult)
+	return results, nil
+}
+
+func encodeAddressCall(selector []byte, accountAddress string) []byte {
+	callData := make([]byte, 0, 36)
+	callData = append(callData, selector...)
+	return append(callData, common.LeftPadBytes(common.HexToAddress(accountAddress).Bytes(), 32)...)
+}
+
+func encodeBalanceOf(accountAddress string) []byte {
+	return encodeAddressCall(erc20BalanceOfSelector, accountAddress)
+}
+
+func encodeGetEthBalance(accountAddress string) []byte {
+	return encodeAddressCall(multicallGetEthBalanceSelector, accountAddress)
+}
+
+func resolveMulticallAddress(multicallAddress string) string {
+	if multicallAddress == "" {
+		return defaultMulticall3Address
+	}
+	return multicallAddress
+}
+
+func decodeUint256(data []byte) (*big.Int, error) {
+	if len(data) != 32 {
+		return nil, fmt.Errorf("unexpected uint256 length: %d", len(data))
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// callMulticall отправляет вызовы пачками по batchSize через aggregate3 и
+// возвращает результаты в том же порядке, в котором были переданы вызовы.
+func callMulticall(ctx context.Context, policy RetryPolicy, rpcURL string, multicallAddress string, calls []multicallCall, batchSize int) ([]multicallResult, error) {
+	multicallAddress = resolveMulticallAddress(multicallAddress)
+	if batchSize <= 0 {
+		batchSize = defaultMulticallBatchSize
+	}
+
+	results := make([]multicallResult, 0, len(calls))
+
+	for start := 0; start < len(calls); start += batchSize {
+		end := min(start+batchSize, len(calls))
+
+		callData, err := encodeAggregate3(calls[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode aggregate3 call: %v", err)
+		}
+
+		var batchResults []multicallResult
+		err = policy.Do(ctx, fmt.Sprintf("aggregate3 batch %d-%d", start, end), func() error {
+			returnData, err := ethCall(ctx, rpcURL, multicallAddress, callData)
+			if err != nil {
+				return err
+			}
+
+			batchResults, err = decodeAggregate3(returnData)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(batchResults) != end-start {
+			return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(batchResults), end-start)
+		}
+
+		results = append(results, batchResults...)
+	}
+
+	return results, nil
+}
+
+func ethCall(ctx context.Context, rpcURL string, to string, callData []byte) ([]byte, error) {
+	params := []interface{}{
+		map[string]string{
+			"to":   to,
+			"data": "0x" + hex.EncodeToString(callData),
+		},
+		"latest",
+	}
+
+	var resultHex string
+	if err := callRpc(ctx, rpcURL, "eth_call", params, &resultHex); err != nil {
+		return nil, err
+	}
+
+	result, err := hex.DecodeString(strings.TrimPrefix(resultHex, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("eth_call returned invalid hex: %v", err)
+	}
+	return result, nil
+}
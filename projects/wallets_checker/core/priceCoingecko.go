@@ -0,0 +1,191 @@
+package core
+
+import (
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	defaultCoingeckoApiKeyHeader = "x-cg-demo-api-key"
+	coingeckoContractsPerRequest = 50
+)
+
+// Соответствие наших названий сетей платформам и нативным монетам CoinGecko.
+// Можно дополнить или переопределить через coingecko.chains в настройках.
+var defaultCoingeckoChains = map[string]customTypes.CoingeckoChainConfig{
+	"eth":   {Platform: "ethereum", NativeCoinID: "ethereum"},
+	"bsc":   {Platform: "binance-smart-chain", NativeCoinID: "binancecoin"},
+	"arb":   {Platform: "arbitrum-one", NativeCoinID: "ethereum"},
+	"op":    {Platform: "optimistic-ethereum", NativeCoinID: "ethereum"},
+	"base":  {Platform: "base", NativeCoinID: "ethereum"},
+	"matic": {Platform: "polygon-pos", NativeCoinID: "polygon-ecosystem-token"},
+	"avax":  {Platform: "avalanche", NativeCoinID: "avalanche-2"},
+	"ftm":   {Platform: "fantom", NativeCoinID: "fantom"},
+	"linea": {Platform: "linea", NativeCoinID: "ethereum"},
+	"era":   {Platform: "zksync", NativeCoinID: "ethereum"},
+	"scrl":  {Platform: "scroll", NativeCoinID: "ethereum"},
+
+	"cosmoshub": {NativeCoinID: "cosmos"},
+	"osmosis":   {NativeCoinID: "osmosis"},
+	"celestia":  {NativeCoinID: "celestia"},
+	"injective": {NativeCoinID: "injective-protocol"},
+	"neutron":   {NativeCoinID: "neutron-3"},
+	"stride":    {NativeCoinID: "stride"},
+	"kava":      {NativeCoinID: "kava"},
+	"sei":       {NativeCoinID: "sei-network"},
+	"axelar":    {NativeCoinID: "axelar"},
+}
+
+// CoingeckoPriceSource работает с любым CoinGecko-совместимым API (/simple/price, /simple/token_price).
+type CoingeckoPriceSource struct {
+	config customTypes.CoingeckoConfig
+}
+
+func NewCoingeckoPriceSource(config customTypes.CoingeckoConfig) *CoingeckoPriceSource {
+	if config.ApiKeyHeader == "" {
+		config.ApiKeyHeader = defaultCoingeckoApiKeyHeader
+	}
+	return &CoingeckoPriceSource{config: config}
+}
+
+func (s *CoingeckoPriceSource) Name() string {
+	return "coingecko"
+}
+
+func (s *CoingeckoPriceSource) chainConfig(chain string) (customTypes.CoingeckoChainConfig, bool) {
+	if chainConfig, exists := s.config.Chains[chain]; exists {
+		return chainConfig, true
+	}
+	chainConfig, exists := defaultCoingeckoChains[chain]
+	return chainConfig, exists
+}
+
+type coingeckoPrice struct {
+	USD           float64 `json:"usd"`
+	LastUpdatedAt int64   `json:"last_updated_at"`
+}
+
+func (s *CoingeckoPriceSource) doRequest(path string, params url.Values) (map[string]coingeckoPrice, error) {
+	client, err := GetClient(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req)
+	req.SetRequestURI(fmt.Sprintf("%s%s?%s", strings.TrimSuffix(s.config.BaseURL, "/"), path, params.Encode()))
+	req.Header.SetMethod(fasthttp.MethodGet)
+	req.Header.Set("accept", "application/json")
+	if s.config.ApiKey != "" {
+		req.Header.Set(s.config.ApiKeyHeader, s.config.ApiKey)
+	}
+
+	resp := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp)
+
+	if err := client.Do(req, resp); err != nil {
+		return nil, fmt.Errorf("%s request error: %v", path, err)
+	}
+
+	if resp.StatusCode() != fasthttp.StatusOK {
+		return nil, fmt.Errorf("%s unexpected status code: %d", path, resp.StatusCode())
+	}
+
+	var result map[string]coingeckoPrice
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("%s failed to parse JSON response: %v", path, err)
+	}
+
+	return result, nil
+}
+
+func (s *CoingeckoPriceSource) toPriceInfo(price coingeckoPrice) customTypes.PriceInfo {
+	timestamp := price.LastUpdatedAt
+	if timestamp == 0 {
+		timestamp = time.Now().Unix()
+	}
+	return customTypes.PriceInfo{USD: price.USD, Timestamp: timestamp, Source: s.Name()}
+}
+
+func (s *CoingeckoPriceSource) GetPrices(keys []customTypes.PriceKey) (map[customTypes.PriceKey]customTypes.PriceInfo, error) {
+	result := make(map[customTypes.PriceKey]customTypes.PriceInfo)
+
+	nativeKeys := make(map[string][]customTypes.PriceKey)
+	contractsByPlatform := make(map[string][]customTypes.PriceKey)
+
+	for _, key := range keys {
+		chainConfig, exists := s.chainConfig(key.Chain)
+		if !exists {
+			continue
+		}
+
+		if key.Contract == "" {
+			if chainConfig.NativeCoinID != "" {
+				nativeKeys[chainConfig.NativeCoinID] = append(nativeKeys[chainConfig.NativeCoinID], key)
+			}
+			continue
+		}
+
+		if chainConfig.Platform != "" {
+			contractsByPlatform[chainConfig.Platform] = append(contractsByPlatform[chainConfig.Platform], key)
+		}
+	}
+
+	if len(nativeKeys) > 0 {
+		coinIDs := make([]string, 0, len(nativeKeys))
+		for coinID := range nativeKeys {
+			coinIDs = append(coinIDs, coinID)
+		}
+
+		params := url.Values{}
+		params.Set("ids", strings.Join(coinIDs, ","))
+		params.Set("vs_currencies", "usd")
+		params.Set("include_last_updated_at", "true")
+
+		prices, err := s.doRequest("/simple/price", params)
+		if err != nil {
+			return nil, err
+		}
+
+		for coinID, price := range prices {
+			for _, key := range nativeKeys[coinID] {
+				result[key] = s.toPriceInfo(price)
+			}
+		}
+	}
+
+	for platform, platformKeys := range contractsByPlatform {
+		for start := 0; start < len(platformKeys); start += coingeckoContractsPerRequest {
+			batch := platformKeys[start:min(start+coingeckoContractsPerRequest, len(platformKeys))]
+
+			contracts := make([]string, 0, len(batch))
+			for _, key := range batch {
+				contracts = append(contracts, key.Contract)
+			}
+
+			params := url.Values{}
+			params.Set("contract_addresses", strings.Join(contracts, ","))
+			params.Set("vs_currencies", "usd")
+			params.Set("include_last_updated_at", "true")
+
+			prices, err := s.doRequest("/simple/token_price/"+platform, params)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, key := range batch {
+				if price, found := prices[key.Contract]; found {
+					result[key] = s.toPriceInfo(price)
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
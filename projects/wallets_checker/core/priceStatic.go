@@ -0,0 +1,68 @@
+package core
+
+import (
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"fmt"
+	"os"
+)
+
+// nativePriceKey - ключ нативного токена сети в файле цен.
+const nativePriceKey = "native"
+
+// StaticPriceSource берет цены из JSON файла вида:
+//
+//	{"updated_at": 1700000000, "prices": {"eth": {"native": 2500, "0xa0b8...": 1}}}
+//
+// Если updated_at не указан, временем цены считается время изменения файла.
+type StaticPriceSource struct {
+	path      string
+	updatedAt int64
+	prices    map[customTypes.PriceKey]float64
+}
+
+func NewStaticPriceSource(path string) (*StaticPriceSource, error) {
+	var fileData struct {
+		UpdatedAt int64                         `json:"updated_at"`
+		Prices    map[string]map[string]float64 `json:"prices"`
+	}
+
+	if err := utils.ReadJson(path, &fileData); err != nil {
+		return nil, fmt.Errorf("failed to load price file %s: %v", path, err)
+	}
+
+	updatedAt := fileData.UpdatedAt
+	if updatedAt == 0 {
+		fileInfo, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat price file %s: %v", path, err)
+		}
+		updatedAt = fileInfo.ModTime().Unix()
+	}
+
+	prices := make(map[customTypes.PriceKey]float64)
+	for chain, chainPrices := range fileData.Prices {
+		for contract, price := range chainPrices {
+			if contract == nativePriceKey {
+				contract = ""
+			}
+			prices[NewPriceKey(chain, contract)] = price
+		}
+	}
+
+	return &StaticPriceSource{path: path, updatedAt: updatedAt, prices: prices}, nil
+}
+
+func (s *StaticPriceSource) Name() string {
+	return "static"
+}
+
+func (s *StaticPriceSource) GetPrices(keys []customTypes.PriceKey) (map[customTypes.PriceKey]customTypes.PriceInfo, error) {
+	result := make(map[customTypes.PriceKey]customTypes.PriceInfo)
+	for _, key := range keys {
+		if price, found := s.prices[key]; found {
+			result[key] = customTypes.PriceInfo{USD: price, Timestamp: s.updatedAt, Source: s.Name()}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,280 @@
+package core
+
+import (
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultPriceCacheTTL = 5 * time.Minute
+
+// PriceSource - источник USD цен для провайдеров, которые возвращают только количество токенов.
+// Отсутствующие в ответе ключи означают, что цена источнику неизвестна.
+type PriceSource interface {
+	Name() string
+	GetPrices(keys []customTypes.PriceKey) (map[customTypes.PriceKey]customTypes.PriceInfo, error)
+}
+
+func NewPriceKey(chain string, contract string) customTypes.PriceKey {
+	return customTypes.PriceKey{Chain: strings.ToLower(chain), Contract: strings.ToLower(contract)}
+}
+
+type cachedPrice struct {
+	price     customTypes.PriceInfo
+	found     bool
+	expiresAt time.Time
+}
+
+// CachedPriceSource кэширует ответы вложенного источника на ttl, включая "цена не найдена",
+// чтобы не запрашивать неизвестные токены при каждой проверке.
+type CachedPriceSource struct {
+	source PriceSource
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[customTypes.PriceKey]cachedPrice
+}
+
+func NewCachedPriceSource(source PriceSource, ttl time.Duration) *CachedPriceSource {
+	if ttl <= 0 {
+		ttl = defaultPriceCacheTTL
+	}
+	return &CachedPriceSource{
+		source: source,
+		ttl:    ttl,
+		cache:  make(map[customTypes.PriceKey]cachedPrice),
+	}
+}
+
+func (s *CachedPriceSource) Name() string {
+	return s.source.Name()
+}
+
+func (s *CachedPriceSource) GetPrices(keys []customTypes.PriceKey) (map[customTypes.PriceKey]customTypes.PriceInfo, error) {
+	result := make(map[customTypes.PriceKey]customTypes.PriceInfo)
+	var missing []customTypes.PriceKey
+	now := time.Now()
+
+	s.mu.Lock()
+	for _, key := range keys {
+		cached, exists := s.cache[key]
+		if !exists || now.After(cached.expiresAt) {
+			missing = append(missing, key)
+			continue
+		}
+		if cached.found {
+			result[key] = cached.price
+		}
+	}
+	s.mu.Unlock()
+
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	fetched, err := s.source.GetPrices(missing)
+	if err != nil {
+		return result, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range missing {
+		price, found := fetched[key]
+		s.cache[key] = cachedPrice{price: price, found: found, expiresAt: now.Add(s.ttl)}
+		if found {
+			result[key] = price
+		}
+	}
+
+	return result, nil
+}
+
+// MultiPriceSource опрашивает источники по порядку: следующий источник получает только те ключи,
+// для которых предыдущие цену не нашли.
+type MultiPriceSource struct {
+	sources []PriceSource
+}
+
+func NewMultiPriceSource(sources ...PriceSource) *MultiPriceSource {
+	return &MultiPriceSource{sources: sources}
+}
+
+func (s *MultiPriceSource) Name() string {
+	names := make([]string, 0, len(s.sources))
+	for _, source := range s.sources {
+		names = append(names, source.Name())
+	}
+	return strings.Join(names, "+")
+}
+
+func (s *MultiPriceSource) GetPrices(keys []customTypes.PriceKey) (map[customTypes.PriceKey]customTypes.PriceInfo, error) {
+	result := make(map[customTypes.PriceKey]customTypes.PriceInfo)
+	missing := keys
+
+	for _, source := range s.sources {
+		if len(missing) == 0 {
+			break
+		}
+
+		prices, err := source.GetPrices(missing)
+		if err != nil {
+			log.Printf("Price Source %s Failed: %v", source.Name(), err)
+			continue
+		}
+
+		var stillMissing []customTypes.PriceKey
+		for _, key := range missing {
+			if price, found := prices[key]; found {
+				result[key] = price
+			} else {
+				stillMissing = append(stillMissing, key)
+			}
+		}
+		missing = stillMissing
+	}
+
+	return result, nil
+}
+
+// maxPriceSources ограничивает число источников с разными настройками: настройки приходят от клиента,
+// а у каждого источника свой кэш цен. При переполнении удаляется самый старый источник.
+const maxPriceSources = 32
+
+var (
+	priceSourcesMu    sync.Mutex
+	priceSources      = make(map[string]PriceSource)
+	priceSourcesOrder []string
+	staticPriceSource PriceSource
+)
+
+// SetStaticPriceFile загружает файл статических цен, заданный при запуске сервера.
+// Изменения файла подхватываются только после перезапуска.
+func SetStaticPriceFile(path string) error {
+	priceSourcesMu.Lock()
+	defer priceSourcesMu.Unlock()
+
+	if path == "" {
+		staticPriceSource = nil
+	} else {
+		source, err := NewStaticPriceSource(path)
+		if err != nil {
+			return err
+		}
+		staticPriceSource = source
+	}
+
+	// Собранные ранее источники ссылаются на старый файл
+	priceSources = make(map[string]PriceSource)
+	priceSourcesOrder = nil
+	return nil
+}
+
+// GetPriceSource собирает источник цен из настроек и файла цен сервера. Источники переиспользуются
+// между запросами с одинаковыми настройками, поэтому кэш цен общий. Без источников возвращает nil.
+func GetPriceSource(config customTypes.PriceConfig) (PriceSource, error) {
+	priceSourcesMu.Lock()
+	defer priceSourcesMu.Unlock()
+
+	if staticPriceSource == nil && config.Coingecko.BaseURL == "" {
+		return nil, nil
+	}
+
+	fingerprint, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if source, exists := priceSources[string(fingerprint)]; exists {
+		return source, nil
+	}
+
+	var sources []PriceSource
+	if staticPriceSource != nil {
+		sources = append(sources, staticPriceSource)
+	}
+	if config.Coingecko.BaseURL != "" {
+		sources = append(sources, NewCoingeckoPriceSource(config.Coingecko))
+	}
+
+	var source PriceSource = NewCachedPriceSource(NewMultiPriceSource(sources...),
+		time.Duration(config.CacheTTL)*time.Second)
+
+	if len(priceSourcesOrder) >= maxPriceSources {
+		delete(priceSources, priceSourcesOrder[0])
+		priceSourcesOrder = priceSourcesOrder[1:]
+	}
+	priceSources[string(fingerprint)] = source
+	priceSourcesOrder = append(priceSourcesOrder, string(fingerprint))
+	return source, nil
+}
+
+// ApplyPrices заполняет BalanceUSD и Price у токенов и пересчитывает TotalBalance.
+// Используется провайдерами, которые сами цен не знают (RPC, Multicall).
+func ApplyPrices(responses []*customTypes.ServerResponse, source PriceSource) error {
+	if source == nil {
+		return nil
+	}
+
+	var keys []customTypes.PriceKey
+	seen := make(map[customTypes.PriceKey]bool)
+
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+		for _, chain := range response.Tokens.Data {
+			for _, token := range chain.Tokens {
+				key := NewPriceKey(chain.ChainName, token.ContractAddress)
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prices, err := source.GetPrices(keys)
+	if err != nil {
+		return fmt.Errorf("failed to get prices: %v", err)
+	}
+
+	for _, response := range responses {
+		if response == nil {
+			continue
+		}
+
+		totalBalance := new(big.Float)
+		for i, chain := range response.Tokens.Data {
+			for j, token := range chain.Tokens {
+				price, found := prices[NewPriceKey(chain.ChainName, token.ContractAddress)]
+				if !found || token.Amount == nil {
+					continue
+				}
+
+				priceCopy := price
+				balanceUSD := new(big.Float).Mul(token.Amount, big.NewFloat(price.USD))
+				response.Tokens.Data[i].Tokens[j].BalanceUSD = balanceUSD
+				response.Tokens.Data[i].Tokens[j].Price = &priceCopy
+				totalBalance.Add(totalBalance, balanceUSD)
+			}
+
+			SortByBalanceUSD(response.Tokens.Data[i].Tokens, func(token customTypes.TokenData) *big.Float {
+				return token.BalanceUSD
+			})
+		}
+
+		response.TotalBalance, _ = totalBalance.Float64()
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// CheckOptions - настройки одной проверки. Передаются в провайдер с каждым вызовом,
+// поэтому параллельные проверки с разными настройками не влияют друг на друга.
+type CheckOptions struct {
+	Proxies []string
+	Config  customTypes.ConfigStruct
+}
+
+// BalanceProvider - источник данных о балансах кошелька.
+// Каждый провайдер регистрирует себя через RegisterProvider в init().
+// При отмене ctx провайдер должен прекратить запросы и вернуть ошибку контекста.
+type BalanceProvider interface {
+	Name() string
+	Capabilities() customTypes.ProviderCapabilities
+	ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error)
+}
+
+// BatchBalanceProvider - опциональное расширение провайдера, которое умеет проверять
+// несколько кошельков за один проход. Ответы и ошибки возвращаются в порядке accountsData.
+type BatchBalanceProvider interface {
+	BalanceProvider
+	ParseAccounts(ctx context.Context, accountsData []string, opts CheckOptions) ([]*customTypes.ServerResponse, []error)
+}
+
+// UnitsMeteredProvider - опциональное расширение провайдера платного API, который сообщает остаток units.
+// Расход считается по остатку до и после всей проверки: кошельки проверяются параллельно, поэтому
+// разница остатков для одного кошелька включала бы чужие запросы. Одновременные проверки
+// на одном ключе так же попадают в расход друг друга.
+type UnitsMeteredProvider interface {
+	BalanceProvider
+	UnitsBalance(ctx context.Context, opts CheckOptions) (float64, error)
+}
+
+// evmFamilies - семейства сетей провайдеров, работающих только с EVM адресами.
+var evmFamilies = []string{utils.FamilyEVM}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]BalanceProvider)
+)
+
+func RegisterProvider(provider BalanceProvider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	name := provider.Name()
+	if _, exists := providers[name]; exists {
+		panic(fmt.Sprintf("balance provider %q already registered", name))
+	}
+	providers[name] = provider
+}
+
+func GetProvider(name string) (BalanceProvider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	provider, exists := providers[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return provider, nil
+}
+
+// ProviderForAccount выбирает провайдер для аккаунта семейства family: preferred, если он
+// поддерживает это семейство, иначе первый по имени подходящий зарегистрированный провайдер.
+func ProviderForAccount(preferred BalanceProvider, family string) (BalanceProvider, error) {
+	if preferred != nil && slices.Contains(preferred.Capabilities().Families, family) {
+		return preferred, nil
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if slices.Contains(providers[name].Capabilities().Families, family) {
+			return providers[name], nil
+		}
+	}
+	return nil, classify(ErrUnsupportedChain, fmt.Errorf("no provider supports %s accounts", family))
+}
+
+func ListProviders() []customTypes.ProviderInfo {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	result := make([]customTypes.ProviderInfo, 0, len(providers))
+	for _, provider := range providers {
+		result = append(result, customTypes.ProviderInfo{
+			Name:         provider.Name(),
+			Capabilities: provider.Capabilities(),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// newServerResponse создает ответ с пустыми (но не nil) списками токенов, NFT и пулов,
+// чтобы фронтенд всегда получал одинаковую структуру.
+func newServerResponse(accountAddress string, accountData string, totalBalance float64) *customTypes.ServerResponse {
+	chainFamily, _ := utils.GetChainFamily(accountData)
+	return &customTypes.ServerResponse{
+		WalletAddress: accountAddress,
+		ChainFamily:   chainFamily,
+		WalletData:    accountData,
+		TotalBalance:  totalBalance,
+		Tokens: customTypes.TokensData{
+			Data: make([]customTypes.ChainTokens, 0),
+		},
+		NFTs: customTypes.NFTsData{
+			Data: make([]customTypes.ChainNfts, 0),
+		},
+		Pools: customTypes.PoolsData{
+			Data: make([]customTypes.ChainPools, 0),
+		},
+	}
+}
+
+// setTokens заполняет response.Tokens и пересчитывает общее количество токенов.
+func setTokens(response *customTypes.ServerResponse, chainTokens []customTypes.ChainTokens) {
+	total := 0
+	for _, chain := range chainTokens {
+		total += len(chain.Tokens)
+	}
+	if chainTokens == nil {
+		chainTokens = make([]customTypes.ChainTokens, 0)
+	}
+	response.Tokens.Quantity = total
+	response.Tokens.Data = chainTokens
+}
+
+func setNfts(response *customTypes.ServerResponse, chainNfts []customTypes.ChainNfts) {
+	total := 0
+	for _, chain := range chainNfts {
+		total += len(chain.Nfts)
+	}
+	response.NFTs.Quantity = total
+	response.NFTs.Data = chainNfts
+}
+
+func setPools(response *customTypes.ServerResponse, chainPools []customTypes.ChainPools) {
+	total := 0
+	for _, chain := range chainPools {
+		for _, protocol := range chain.Protocols {
+			total += len(protocol.Pools)
+		}
+	}
+	response.Pools.Quantity = total
+	response.Pools.Data = chainPools
+}
+
+// addResponseErrors сохраняет в ответе ошибки частей, которые не удалось получить.
+// Ответ при этом остается валидным, но неполным.
+func addResponseErrors(response *customTypes.ServerResponse, errs ...error) {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		log.Printf("%s | Partial Result: %v", response.WalletAddress, err)
+		response.Errors = append(response.Errors, err.Error())
+	}
+}
@@ -1,8 +1,8 @@
 package core
 
 import (
+	"context"
 	"debank_checker_v3/customTypes"
-	"debank_checker_v3/utils"
 	"encoding/json"
 	"fmt"
 	"github.com/valyala/fasthttp"
@@ -19,7 +19,7 @@ func SortByChainBalance(data []customTypes.RabbyReturnData) {
 	})
 }
 
-func getTotalBalance(accountAddress string, proxies []string) (float64, []customTypes.RabbyReturnData) {
+func getTotalBalance(ctx context.Context, policy RetryPolicy, accountAddress string, proxies []string) (float64, []customTypes.RabbyReturnData, error) {
 	baseURL := "https://api.rabby.io/v1/user/total_balance"
 	params := url.Values{}
 	params.Set("id", accountAddress)
@@ -37,9 +37,14 @@ func getTotalBalance(accountAddress string, proxies []string) (float64, []custom
 		Message       string      `json:"message,omitempty"`
 	}
 
-	for {
-		client := GetClient(proxies)
-		var result []customTypes.RabbyReturnData
+	var totalUsdBalance float64
+	var result []customTypes.RabbyReturnData
+
+	err := policy.Do(ctx, accountAddress+" | rabby total_balance", func() error {
+		client, err := GetClient(proxies)
+		if err != nil {
+			return err
+		}
 
 		req := fasthttp.AcquireRequest()
 		defer fasthttp.ReleaseRequest(req)
@@ -50,29 +55,33 @@ func getTotalBalance(accountAddress string, proxies []string) (float64, []custom
 		resp := fasthttp.AcquireResponse()
 		defer fasthttp.ReleaseResponse(resp)
 
-		if err := client.Do(req, resp); err != nil {
-			log.Printf("%s | Request Error: %s", accountAddress, err)
-			continue
+		if err := doWithContext(ctx, client, req, resp); err != nil {
+			return fmt.Errorf("request error: %w", err)
 		}
 
+		// Rabby отвечает 403 при превышении лимита, поэтому его тоже повторяем
 		if resp.StatusCode() == 429 || resp.StatusCode() == 403 {
-			log.Printf("%s | Rate Limited", accountAddress)
-			continue
+			return retryAfter(classify(ErrRateLimited, fmt.Errorf("status code %d", resp.StatusCode())),
+				parseRetryAfter(string(resp.Header.Peek("Retry-After"))))
+		}
+
+		if resp.StatusCode() >= 400 {
+			return statusError(fmt.Errorf("unexpected status code: %d", resp.StatusCode()),
+				resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
 		}
 
 		responseData := &responseStruct{}
 
 		if err := json.Unmarshal(resp.Body(), &responseData); err != nil {
-			log.Printf("%s | Failed To Parse JSON Response: %s", accountAddress, err)
-			continue
+			return classify(ErrUpstreamSchemaChanged, fmt.Errorf("failed to parse JSON response: %v", err))
 		}
 
 		if responseData.Message == "Too Many Requests" {
-			log.Printf("%s | Rate Limited", accountAddress)
-			continue
+			return classify(ErrRateLimited, fmt.Errorf("too many requests"))
 		}
 
-		totalUsdBalance := responseData.TotalUsdValue
+		totalUsdBalance = responseData.TotalUsdValue
+		result = nil
 
 		for _, currentChain := range responseData.ChainList {
 			if currentChain.UsdBalance <= 0 {
@@ -83,30 +92,46 @@ func getTotalBalance(accountAddress string, proxies []string) (float64, []custom
 				ChainBalance: currentChain.UsdBalance})
 		}
 
-		return totalUsdBalance, result
-	}
+		return nil
+	})
+
+	return totalUsdBalance, result, err
 }
 
-func ParseRabbyAccount(accountData string, proxies []string) (*customTypes.ServerResponse, error) {
-	accountAddress, err := utils.GetAccountAddress(accountData)
+type rabbyProvider struct{}
+
+func init() {
+	RegisterProvider(rabbyProvider{})
+}
+
+func (rabbyProvider) Name() string {
+	return "rabby"
+}
+
+func (rabbyProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Tokens: true, Families: evmFamilies}
+}
+
+func (rabbyProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	return ParseRabbyAccount(ctx, accountData, opts.Proxies, opts.Config)
+}
+
+func ParseRabbyAccount(ctx context.Context, accountData string, proxies []string, config customTypes.ConfigStruct) (*customTypes.ServerResponse, error) {
+	accountAddress, err := getAccountAddress(accountData, evmFamilies)
 	if err != nil {
 		return nil, err
 	}
 
-	totalUsdBalance, chainBalances := getTotalBalance(accountAddress, proxies)
-	SortByChainBalance(chainBalances)
-
-	response := &customTypes.ServerResponse{
-		WalletAddress: accountAddress,
-		WalletData:    accountData,
-		TotalBalance:  totalUsdBalance,
+	totalUsdBalance, chainBalances, err := getTotalBalance(ctx, GetRetryPolicy(config, "rabby"), accountAddress, proxies)
+	if err != nil {
+		return nil, err
 	}
+	SortByChainBalance(chainBalances)
 
-	// Инициализируем пустые структуры для токенов, NFT и пулов
-	chainTokens := make([]customTypes.ChainTokens, 0)
-	totalTokens := 0
+	response := newServerResponse(accountAddress, accountData, totalUsdBalance)
 
 	// Преобразуем RabbyReturnData в формат ChainTokens
+	chainTokens := make([]customTypes.ChainTokens, 0)
 	for _, chainBalance := range chainBalances {
 		chainTokens = append(chainTokens, customTypes.ChainTokens{
 			ChainName: chainBalance.ChainName,
@@ -115,22 +140,12 @@ func ParseRabbyAccount(accountData string, proxies []string) (*customTypes.Serve
 					Name:            chainBalance.ChainName + " Native Token",
 					BalanceUSD:      big.NewFloat(chainBalance.ChainBalance),
 					Amount:          big.NewFloat(0), // У нас нет этих данных из Rabby
-					ContractAddress: "", // У нас нет этих данных из Rabby
+					ContractAddress: "",              // У нас нет этих данных из Rabby
 				},
 			},
 		})
-		totalTokens++
 	}
-
-	// Заполняем данные о токенах
-	response.Tokens.Quantity = totalTokens
-	response.Tokens.Data = chainTokens
-
-	// Инициализируем пустые NFT и пулы
-	response.NFTs.Quantity = 0
-	response.NFTs.Data = []customTypes.ChainNfts{}
-	response.Pools.Quantity = 0
-	response.Pools.Data = []customTypes.ChainPools{}
+	setTokens(response, chainTokens)
 
 	log.Printf("%s | Total USD Balance: %f $", accountAddress, totalUsdBalance)
 	return response, nil
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// GetRetryPolicy возвращает политику повторов провайдера из retry_config.
+// Не заданные поля берутся из DefaultRetryPolicy.
+func GetRetryPolicy(config customTypes.ConfigStruct, providerName string) RetryPolicy {
+	policy := DefaultRetryPolicy
+
+	retryConfig, exists := config.RetryConfig[providerName]
+	if !exists {
+		return policy
+	}
+
+	if retryConfig.MaxAttempts > 0 {
+		policy.MaxAttempts = retryConfig.MaxAttempts
+	}
+	if retryConfig.BaseDelayMs > 0 {
+		policy.BaseDelay = time.Duration(retryConfig.BaseDelayMs) * time.Millisecond
+	}
+	if retryConfig.MaxDelayMs > 0 {
+		policy.MaxDelay = time.Duration(retryConfig.MaxDelayMs) * time.Millisecond
+	}
+	return policy
+}
+
+// permanentError - ошибка, которую бесполезно повторять (неверный запрос, 4xx кроме 429).
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// retryAfterError - временная ошибка, для которой сервер подсказал, сколько ждать перед повтором.
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+func retryAfter(err error, after time.Duration) error {
+	return &retryAfterError{err: err, after: after}
+}
+
+// parseRetryAfter разбирает заголовок Retry-After: число секунд или HTTP дата.
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// statusError классифицирует HTTP статус: 429 (ErrRateLimited) и 5xx (ErrUpstreamUnavailable)
+// повторяются с учетом Retry-After, остальные 4xx считаются постоянными ошибками.
+func statusError(err error, statusCode int, retryAfterHeader string) error {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return retryAfter(classify(ErrRateLimited, err), parseRetryAfter(retryAfterHeader))
+	case statusCode >= 500:
+		return retryAfter(classify(ErrUpstreamUnavailable, err), parseRetryAfter(retryAfterHeader))
+	case statusCode >= 400:
+		return permanent(classify(ErrUpstreamUnavailable, err))
+	}
+	return err
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Jitter: случайная задержка в диапазоне [delay/2, delay]
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Do вызывает fn, пока она не вернет nil, постоянную ошибку или не закончатся попытки.
+// Между попытками ждет экспоненциальную задержку с jitter либо время из Retry-After,
+// если оно не больше MaxDelay. На больший Retry-After сразу возвращается последняя ошибка.
+func (p RetryPolicy) Do(ctx context.Context, name string, fn func() error) error {
+	maxAttempts := max(p.MaxAttempts, 1)
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		var permanentErr *permanentError
+		if errors.As(lastErr, &permanentErr) {
+			return lastErr
+		}
+
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		var retryAfterErr *retryAfterError
+		if errors.As(lastErr, &retryAfterErr) && retryAfterErr.after > 0 {
+			// Дольше MaxDelay не ждем: у задач нет дедлайна, и воркер простоял бы весь Retry-After
+			if p.MaxDelay > 0 && retryAfterErr.after > p.MaxDelay {
+				return fmt.Errorf("%s | giving up: retry after %s exceeds max delay %s: %w", name, retryAfterErr.after, p.MaxDelay, lastErr)
+			}
+			delay = retryAfterErr.after
+		}
+
+		log.Printf("%s | Attempt %d/%d Failed: %v | Retrying In %s", name, attempt, maxAttempts, lastErr, delay.Round(time.Millisecond))
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%s | giving up after %d attempts: %w", name, maxAttempts, lastErr)
+}
+
+// sleepContext ждет delay или отмены ctx.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,246 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const evmNativeDecimals = 18
+
+type rpcProvider struct{}
+
+func init() {
+	RegisterProvider(rpcProvider{})
+}
+
+func (rpcProvider) Name() string {
+	return "evm_rpc"
+}
+
+func (rpcProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Tokens: true, Families: evmFamilies}
+}
+
+func (p rpcProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	responses, errs := p.ParseAccounts(ctx, []string{accountData}, opts)
+	return responses[0], errs[0]
+}
+
+func (rpcProvider) ParseAccounts(ctx context.Context, accountsData []string, opts CheckOptions) ([]*customTypes.ServerResponse, []error) {
+	responses, errs := ParseRpcAccounts(ctx, accountsData, opts.Config.RpcConfig, GetRetryPolicy(opts.Config, "evm_rpc"))
+
+	priceSource, err := GetPriceSource(opts.Config.PriceConfig)
+	if err != nil {
+		log.Printf("Failed To Init Price Source: %v", err)
+		return responses, errs
+	}
+
+	if err = ApplyPrices(responses, priceSource); err != nil {
+		log.Printf("Failed To Apply Prices: %v", err)
+	}
+	return responses, errs
+}
+
+// getChainBalances читает нативный баланс (getEthBalance самого Multicall3) и balanceOf каждого токена
+// для всех кошельков одним набором aggregate3 вызовов - один запрос к сети на пачку.
+// Результат: адрес кошелька -> ненулевые балансы, а также ошибки чтения нативного баланса по адресам.
+func getChainBalances(ctx context.Context, policy RetryPolicy, chainConfig customTypes.RpcChainConfig, nativeSymbol string, accountAddresses []string) (map[string][]customTypes.TokenData, map[string]error, error) {
+	result := make(map[string][]customTypes.TokenData)
+	nativeErrs := make(map[string]error)
+	if len(accountAddresses) == 0 {
+		return result, nativeErrs, nil
+	}
+
+	multicallTarget := common.HexToAddress(resolveMulticallAddress(chainConfig.Multicall))
+	stride := 1 + len(chainConfig.Tokens)
+
+	calls := make([]multicallCall, 0, len(accountAddresses)*stride)
+	for _, accountAddress := range accountAddresses {
+		calls = append(calls, multicallCall{
+			Target:       multicallTarget,
+			AllowFailure: true,
+			CallData:     encodeGetEthBalance(accountAddress),
+		})
+		for _, token := range chainConfig.Tokens {
+			calls = append(calls, multicallCall{
+				Target:       common.HexToAddress(token.Contract),
+				AllowFailure: true,
+				CallData:     encodeBalanceOf(accountAddress),
+			})
+		}
+	}
+
+	callResults, err := callMulticall(ctx, policy, chainConfig.RpcURL, chainConfig.Multicall, calls, chainConfig.BatchSize)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i, callResult := range callResults {
+		accountAddress := accountAddresses[i/stride]
+
+		// Первый вызов каждого кошелька - нативный баланс
+		if i%stride == 0 {
+			if !callResult.Success {
+				nativeErrs[accountAddress] = fmt.Errorf("getEthBalance call failed")
+				continue
+			}
+			balance, err := decodeUint256(callResult.ReturnData)
+			if err != nil {
+				nativeErrs[accountAddress] = err
+				continue
+			}
+			if balance.Sign() > 0 {
+				result[accountAddress] = append(result[accountAddress], customTypes.TokenData{
+					Name:            nativeSymbol,
+					BalanceUSD:      new(big.Float),
+					Amount:          scaleByDecimals(balance, evmNativeDecimals),
+					ContractAddress: "",
+				})
+			}
+			continue
+		}
+
+		token := chainConfig.Tokens[i%stride-1]
+
+		if !callResult.Success {
+			continue
+		}
+
+		balance, err := decodeUint256(callResult.ReturnData)
+		if err != nil || balance.Sign() == 0 {
+			continue
+		}
+
+		result[accountAddress] = append(result[accountAddress], customTypes.TokenData{
+			Name:            token.Symbol,
+			BalanceUSD:      new(big.Float),
+			Amount:          scaleByDecimals(balance, token.Decimals),
+			ContractAddress: strings.ToLower(token.Contract),
+		})
+	}
+
+	return result, nativeErrs, nil
+}
+
+func sortedChainNames(chains map[string]customTypes.RpcChainConfig) []string {
+	names := make([]string, 0, len(chains))
+	for name := range chains {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRpcAccount читает нативные балансы и балансы ERC-20 токенов напрямую с EVM нод, без сторонних API.
+func ParseRpcAccount(ctx context.Context, accountData string, config customTypes.RpcConfig, policy RetryPolicy) (*customTypes.ServerResponse, error) {
+	responses, errs := ParseRpcAccounts(ctx, []string{accountData}, config, policy)
+	return responses[0], errs[0]
+}
+
+// ParseRpcAccounts проверяет сразу несколько кошельков. Нативные балансы и токены читаются
+// пачками через Multicall3 для всех кошельков сразу.
+func ParseRpcAccounts(ctx context.Context, accountsData []string, config customTypes.RpcConfig, policy RetryPolicy) ([]*customTypes.ServerResponse, []error) {
+	responses := make([]*customTypes.ServerResponse, len(accountsData))
+	errs := make([]error, len(accountsData))
+
+	if len(config.Chains) == 0 {
+		for i := range errs {
+			errs[i] = classify(ErrInvalidConfig, fmt.Errorf("no rpc chains configured"))
+		}
+		return responses, errs
+	}
+
+	// Один и тот же адрес может встречаться несколько раз (например, mnemonic и его приватный ключ)
+	var accountAddresses []string
+	accountIndexes := make(map[string][]int)
+
+	for i, accountData := range accountsData {
+		accountAddress, err := getAccountAddress(accountData, evmFamilies)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		responses[i] = newServerResponse(accountAddress, accountData, 0)
+		if _, exists := accountIndexes[accountAddress]; !exists {
+			accountAddresses = append(accountAddresses, accountAddress)
+		}
+		accountIndexes[accountAddress] = append(accountIndexes[accountAddress], i)
+	}
+
+	chainTokens := make(map[string][]customTypes.ChainTokens)
+	failedChains := make(map[string]int)
+	chainErrors := make(map[string][]error)
+
+	for _, chainName := range sortedChainNames(config.Chains) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		chainConfig := config.Chains[chainName]
+
+		symbol := chainConfig.NativeSymbol
+		if symbol == "" {
+			symbol = chainName + " Native Token"
+		}
+
+		balances, nativeErrs, err := getChainBalances(ctx, policy, chainConfig, symbol, accountAddresses)
+		if err != nil {
+			log.Printf("%s | Failed To Get Balances: %v", chainName, err)
+		}
+
+		for _, accountAddress := range accountAddresses {
+			if err != nil {
+				failedChains[accountAddress]++
+				chainErrors[accountAddress] = append(chainErrors[accountAddress], fmt.Errorf("%s | %w", chainName, err))
+				continue
+			}
+			if nativeErr := nativeErrs[accountAddress]; nativeErr != nil {
+				log.Printf("%s | %s | Failed To Get Native Balance: %v", accountAddress, chainName, nativeErr)
+				chainErrors[accountAddress] = append(chainErrors[accountAddress], fmt.Errorf("%s | native balance: %w", chainName, nativeErr))
+			}
+
+			tokens := balances[accountAddress]
+			if len(tokens) == 0 {
+				continue
+			}
+
+			chainTokens[accountAddress] = append(chainTokens[accountAddress], customTypes.ChainTokens{
+				ChainName: chainName,
+				Tokens:    tokens,
+			})
+		}
+	}
+
+	ctxErr := ctx.Err()
+
+	for _, accountAddress := range accountAddresses {
+		for _, i := range accountIndexes[accountAddress] {
+			if ctxErr != nil {
+				responses[i] = nil
+				errs[i] = ctxErr
+				continue
+			}
+
+			if failedChains[accountAddress] == len(config.Chains) {
+				responses[i] = nil
+				lastErr := chainErrors[accountAddress][len(chainErrors[accountAddress])-1]
+				errs[i] = classify(ErrUpstreamUnavailable, fmt.Errorf("%s | failed to get balance from all rpc chains: %w", accountAddress, lastErr))
+				continue
+			}
+
+			setTokens(responses[i], chainTokens[accountAddress])
+			addResponseErrors(responses[i], chainErrors[accountAddress]...)
+		}
+		log.Printf("%s | Balances Found On %d Chains", accountAddress, len(chainTokens[accountAddress]))
+	}
+
+	return responses, errs
+}
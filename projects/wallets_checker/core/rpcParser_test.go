@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"debank_checker_v3/customTypes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var testRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// rpcStub - локальная JSON-RPC нода. handle возвращает result или ошибку ноды для метода,
+// calls считает вызовы каждого метода.
+type rpcStub struct {
+	*httptest.Server
+	mu     sync.Mutex
+	calls  map[string]int
+	handle func(method string, params []json.RawMessage) (interface{}, *rpcError)
+}
+
+func newRpcStub(t *testing.T, handle func(method string, params []json.RawMessage) (interface{}, *rpcError)) *rpcStub {
+	t.Helper()
+
+	stub := &rpcStub{calls: make(map[string]int), handle: handle}
+	stub.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		stub.calls[req.Method]++
+		stub.mu.Unlock()
+
+		result, rpcErr := stub.handle(req.Method, req.Params)
+		response := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if rpcErr != nil {
+			response["error"] = rpcErr
+		} else {
+			response["result"] = result
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (s *rpcStub) count(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// multicallHandler разбирает eth_call к aggregate3 и отвечает на каждый вложенный вызов через call.
+func multicallHandler(t *testing.T, params []json.RawMessage, call func(target common.Address, callData []byte) multicallResult) interface{} {
+	t.Helper()
+
+	var tx struct {
+		Data string `json:"data"`
+	}
+	if err := json.Unmarshal(params[0], &tx); err != nil {
+		t.Fatalf("invalid eth_call params: %v", err)
+	}
+	data, err := hex.DecodeString(strings.TrimPrefix(tx.Data, "0x"))
+	if err != nil {
+		t.Fatalf("invalid eth_call data: %v", err)
+	}
+
+	method := multicall3ABI.Methods["aggregate3"]
+	unpacked, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("failed to unpack aggregate3: %v", err)
+	}
+	calls := *abi.ConvertType(unpacked[0], new([]multicallCall)).(*[]multicallCall)
+
+	results := make([]multicallResult, len(calls))
+	for i, c := range calls {
+		results[i] = call(c.Target, c.CallData)
+	}
+
+	packed, err := method.Outputs.Pack(results)
+	if err != nil {
+		t.Fatalf("failed to pack aggregate3 results: %v", err)
+	}
+	return "0x" + hex.EncodeToString(packed)
+}
+
+func uint256Result(value *big.Int) multicallResult {
+	return multicallResult{Success: true, ReturnData: common.LeftPadBytes(value.Bytes(), 32)}
+}
+
+func TestParseRpcAccountsDuplicateAddresses(t *testing.T) {
+	const (
+		walletA = "0x1111111111111111111111111111111111111111"
+		walletB = "0x2222222222222222222222222222222222222222"
+		usdc    = "0x3333333333333333333333333333333333333333"
+		weth    = "0x4444444444444444444444444444444444444444"
+	)
+
+	oneEther := new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+	// Нативный баланс отдает getEthBalance самого Multicall3, поэтому он лежит по адресу мультиколла
+	balances := map[string]map[string]*big.Int{
+		walletA: {strings.ToLower(defaultMulticall3Address): oneEther, usdc: big.NewInt(2_500_000)},
+		walletB: {weth: oneEther},
+	}
+
+	stub := newRpcStub(t, func(method string, params []json.RawMessage) (interface{}, *rpcError) {
+		switch method {
+		case "eth_call":
+			return multicallHandler(t, params, func(target common.Address, callData []byte) multicallResult {
+				selector := callData[:4]
+				if strings.EqualFold(target.Hex(), defaultMulticall3Address) != bytes.Equal(selector, multicallGetEthBalanceSelector) {
+					t.Errorf("unexpected call %x to %s", selector, target.Hex())
+				}
+				owner := strings.ToLower(common.BytesToAddress(callData[4:36]).Hex())
+				balance, found := balances[owner][strings.ToLower(target.Hex())]
+				if !found {
+					balance = new(big.Int)
+				}
+				return uint256Result(balance)
+			}), nil
+		}
+		return nil, &rpcError{Code: -32601, Message: "method not found"}
+	})
+
+	config := customTypes.RpcConfig{Chains: map[string]customTypes.RpcChainConfig{
+		"eth": {
+			RpcURL:       stub.URL,
+			NativeSymbol: "ETH",
+			BatchSize:    3,
+			Tokens: []customTypes.RpcTokenConfig{
+				{Contract: usdc, Symbol: "USDC", Decimals: 6},
+				{Contract: weth, Symbol: "WETH", Decimals: 18},
+			},
+		},
+	}}
+
+	responses, errs := ParseRpcAccounts(context.Background(), []string{walletA, walletB, walletA}, config, testRetryPolicy)
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("account %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// Повторный адрес не запрашивается второй раз: 2 кошелька x (нативный баланс + 2 токена) пачками по 3 вызова
+	if got := stub.count("eth_getBalance"); got != 0 {
+		t.Errorf("eth_getBalance calls = %d, want 0", got)
+	}
+	if got := stub.count("eth_call"); got != 2 {
+		t.Errorf("eth_call calls = %d, want 2", got)
+	}
+
+	for _, i := range []int{0, 2} {
+		response := responses[i]
+		if !strings.EqualFold(response.WalletAddress, walletA) {
+			t.Fatalf("response %d: wallet = %s, want %s", i, response.WalletAddress, walletA)
+		}
+		if len(response.Tokens.Data) != 1 || len(response.Tokens.Data[0].Tokens) != 2 {
+			t.Fatalf("response %d: tokens = %+v, want ETH and USDC on eth", i, response.Tokens.Data)
+		}
+
+		tokens := response.Tokens.Data[0].Tokens
+		if tokens[0].Name != "ETH" || tokens[0].Amount.Text('f', -1) != "1" {
+			t.Errorf("response %d: native = %s %s, want 1 ETH", i, tokens[0].Amount.Text('f', -1), tokens[0].Name)
+		}
+		if tokens[1].Name != "USDC" || tokens[1].Amount.Text('f', -1) != "2.5" {
+			t.Errorf("response %d: token = %s %s, want 2.5 USDC", i, tokens[1].Amount.Text('f', -1), tokens[1].Name)
+		}
+	}
+
+	walletBTokens := responses[1].Tokens.Data
+	if len(walletBTokens) != 1 || len(walletBTokens[0].Tokens) != 1 || walletBTokens[0].Tokens[0].Name != "WETH" {
+		t.Fatalf("wallet B tokens = %+v, want only WETH", walletBTokens)
+	}
+}
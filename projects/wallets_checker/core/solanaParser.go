@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+)
+
+const (
+	solanaChainName    = "sol"
+	solanaNativeSymbol = "SOL"
+	lamportsDecimals   = 9
+
+	splTokenProgram  = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	token2022Program = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+)
+
+var solanaFamilies = []string{utils.FamilySolana}
+
+type solanaProvider struct{}
+
+func init() {
+	RegisterProvider(solanaProvider{})
+}
+
+func (solanaProvider) Name() string {
+	return "solana_rpc"
+}
+
+func (solanaProvider) Capabilities() customTypes.ProviderCapabilities {
+	return customTypes.ProviderCapabilities{Tokens: true, Families: solanaFamilies}
+}
+
+func (solanaProvider) ParseAccount(ctx context.Context, accountData string, opts CheckOptions) (*customTypes.ServerResponse, error) {
+	response, err := ParseSolanaAccount(ctx, accountData, opts.Config.SolanaConfig, GetRetryPolicy(opts.Config, "solana_rpc"))
+	if err != nil {
+		return nil, err
+	}
+
+	priceSource, err := GetPriceSource(opts.Config.PriceConfig)
+	if err != nil {
+		log.Printf("Failed To Init Price Source: %v", err)
+		return response, nil
+	}
+
+	if err = ApplyPrices([]*customTypes.ServerResponse{response}, priceSource); err != nil {
+		log.Printf("Failed To Apply Prices: %v", err)
+	}
+	return response, nil
+}
+
+type solanaTokenInfo struct {
+	Address  string `json:"address"`
+	Symbol   string `json:"symbol"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`
+}
+
+var (
+	solanaTokenListMu sync.Mutex
+	solanaTokenList   map[string]solanaTokenInfo
+)
+
+// SetSolanaTokenList загружает список токенов (mint -> метаданные), заданный при запуске сервера.
+// Путь не берется из запроса, чтобы клиент API не мог заставить сервер читать произвольный файл.
+func SetSolanaTokenList(path string) error {
+	tokens := make(map[string]solanaTokenInfo)
+	if path != "" {
+		var fileData struct {
+			Tokens []solanaTokenInfo `json:"tokens"`
+		}
+		if err := utils.ReadJson(path, &fileData); err != nil {
+			return fmt.Errorf("failed to load solana token list %s: %v", path, err)
+		}
+		for _, token := range fileData.Tokens {
+			tokens[token.Address] = token
+		}
+	}
+
+	solanaTokenListMu.Lock()
+	solanaTokenList = tokens
+	solanaTokenListMu.Unlock()
+	return nil
+}
+
+func getSolanaTokenList() map[string]solanaTokenInfo {
+	solanaTokenListMu.Lock()
+	defer solanaTokenListMu.Unlock()
+	return solanaTokenList
+}
+
+func getSolanaBalance(ctx context.Context, policy RetryPolicy, rpcURL string, accountAddress string) (*big.Int, error) {
+	var result struct {
+		Value uint64 `json:"value"`
+	}
+	err := policy.Do(ctx, accountAddress+" | getBalance", func() error {
+		return callRpc(ctx, rpcURL, "getBalance", []interface{}{accountAddress, map[string]string{"commitment": "confirmed"}}, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(result.Value), nil
+}
+
+type solanaTokenAmount struct {
+	Mint     string
+	Amount   *big.Int
+	Decimals int
+}
+
+// getSolanaTokenAccounts возвращает балансы всех токен-аккаунтов владельца в программе programID.
+func getSolanaTokenAccounts(ctx context.Context, policy RetryPolicy, rpcURL string, accountAddress string, programID string) ([]solanaTokenAmount, error) {
+	var result struct {
+		Value []struct {
+			Account struct {
+				Data struct {
+					Parsed struct {
+						Info struct {
+							Mint        string `json:"mint"`
+							TokenAmount struct {
+								Amount   string `json:"amount"`
+								Decimals int    `json:"decimals"`
+							} `json:"tokenAmount"`
+						} `json:"info"`
+					} `json:"parsed"`
+				} `json:"data"`
+			} `json:"account"`
+		} `json:"value"`
+	}
+
+	params := []interface{}{
+		accountAddress,
+		map[string]string{"programId": programID},
+		map[string]string{"encoding": "jsonParsed", "commitment": "confirmed"},
+	}
+	err := policy.Do(ctx, accountAddress+" | getTokenAccountsByOwner", func() error {
+		return callRpc(ctx, rpcURL, "getTokenAccountsByOwner", params, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := make([]solanaTokenAmount, 0, len(result.Value))
+	for _, tokenAccount := range result.Value {
+		info := tokenAccount.Account.Data.Parsed.Info
+		amount, ok := new(big.Int).SetString(info.TokenAmount.Amount, 10)
+		if !ok {
+			return nil, classify(ErrUpstreamSchemaChanged, fmt.Errorf("invalid token amount %q for mint %s", info.TokenAmount.Amount, info.Mint))
+		}
+		amounts = append(amounts, solanaTokenAmount{Mint: info.Mint, Amount: amount, Decimals: info.TokenAmount.Decimals})
+	}
+	return amounts, nil
+}
+
+// ParseSolanaAccount читает баланс SOL и токенов SPL Token / Token-2022 напрямую с ноды Solana.
+// Метаданные токенов берутся из локального списка, неизвестные токены называются адресом mint.
+func ParseSolanaAccount(ctx context.Context, accountData string, config customTypes.SolanaConfig, policy RetryPolicy) (*customTypes.ServerResponse, error) {
+	accountAddress, err := getAccountAddress(accountData, solanaFamilies)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.RpcURL == "" {
+		return nil, classify(ErrInvalidConfig, fmt.Errorf("solana rpc url is not set"))
+	}
+
+	tokenList := getSolanaTokenList()
+
+	var partErrs []error
+	tokens := make([]customTypes.TokenData, 0)
+
+	balance, nativeErr := getSolanaBalance(ctx, policy, config.RpcURL, accountAddress)
+	if nativeErr != nil {
+		log.Printf("%s | Failed To Get SOL Balance: %v", accountAddress, nativeErr)
+		partErrs = append(partErrs, fmt.Errorf("native balance: %w", nativeErr))
+	} else if balance.Sign() > 0 {
+		tokens = append(tokens, customTypes.TokenData{
+			Name:            solanaNativeSymbol,
+			BalanceUSD:      new(big.Float),
+			Amount:          scaleByDecimals(balance, lamportsDecimals),
+			ContractAddress: "",
+		})
+	}
+
+	// У одного владельца может быть несколько токен-аккаунтов одного mint, суммируем их
+	mintAmounts := make(map[string]*solanaTokenAmount)
+	failedPrograms := 0
+	for _, programID := range []string{splTokenProgram, token2022Program} {
+		amounts, err := getSolanaTokenAccounts(ctx, policy, config.RpcURL, accountAddress, programID)
+		if err != nil {
+			log.Printf("%s | Failed To Get Token Accounts Of %s: %v", accountAddress, programID, err)
+			partErrs = append(partErrs, fmt.Errorf("token accounts %s: %w", programID, err))
+			failedPrograms++
+			continue
+		}
+
+		for _, amount := range amounts {
+			if existing, exists := mintAmounts[amount.Mint]; exists {
+				existing.Amount.Add(existing.Amount, amount.Amount)
+				continue
+			}
+			mintAmounts[amount.Mint] = &amount
+		}
+	}
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if nativeErr != nil && failedPrograms == 2 {
+		return nil, classify(ErrUpstreamUnavailable, fmt.Errorf("%s | failed to get solana balances: %w", accountAddress, nativeErr))
+	}
+
+	mints := make([]string, 0, len(mintAmounts))
+	for mint, amount := range mintAmounts {
+		if amount.Amount.Sign() > 0 {
+			mints = append(mints, mint)
+		}
+	}
+	sort.Strings(mints)
+
+	for _, mint := range mints {
+		amount := mintAmounts[mint]
+		name := mint
+		if token, known := tokenList[mint]; known && token.Symbol != "" {
+			name = token.Symbol
+		}
+
+		tokens = append(tokens, customTypes.TokenData{
+			Name:            name,
+			BalanceUSD:      new(big.Float),
+			Amount:          scaleByDecimals(amount.Amount, amount.Decimals),
+			ContractAddress: mint,
+		})
+	}
+
+	response := newServerResponse(accountAddress, accountData, 0)
+	if len(tokens) > 0 {
+		setTokens(response, []customTypes.ChainTokens{{ChainName: solanaChainName, Tokens: tokens}})
+	}
+	addResponseErrors(response, partErrs...)
+
+	log.Printf("%s | Found %d Solana Tokens", accountAddress, len(tokens))
+	return response, nil
+}
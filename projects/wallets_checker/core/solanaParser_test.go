@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"testing"
+)
+
+func solanaTokenAccount(mint string, amount string, decimals int) map[string]interface{} {
+	return map[string]interface{}{
+		"account": map[string]interface{}{
+			"data": map[string]interface{}{
+				"parsed": map[string]interface{}{
+					"info": map[string]interface{}{
+						"mint":        mint,
+						"tokenAmount": map[string]interface{}{"amount": amount, "decimals": decimals},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParseSolanaAccountAggregatesTokenAccounts(t *testing.T) {
+	const (
+		wallet    = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+		usdcMint  = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+		emptyMint = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+		pyusdMint = "2b1kV6DkPAnxd5ixfnxCpjxmKwqjjaYmCZfHsFu24GXo"
+	)
+
+	stub := newRpcStub(t, func(method string, params []json.RawMessage) (interface{}, *rpcError) {
+		switch method {
+		case "getBalance":
+			return map[string]interface{}{"value": 1_500_000_000}, nil
+		case "getTokenAccountsByOwner":
+			var filter struct {
+				ProgramID string `json:"programId"`
+			}
+			json.Unmarshal(params[1], &filter)
+
+			// Два токен-аккаунта одного mint и пустой аккаунт в SPL Token, еще один mint в Token-2022
+			if filter.ProgramID == splTokenProgram {
+				return map[string]interface{}{"value": []interface{}{
+					solanaTokenAccount(usdcMint, "1000000", 6),
+					solanaTokenAccount(emptyMint, "0", 6),
+					solanaTokenAccount(usdcMint, "2500000", 6),
+				}}, nil
+			}
+			return map[string]interface{}{"value": []interface{}{
+				solanaTokenAccount(pyusdMint, "42", 0),
+			}}, nil
+		}
+		return nil, &rpcError{Code: -32601, Message: "method not found"}
+	})
+
+	response, err := ParseSolanaAccount(context.Background(), wallet, customTypes.SolanaConfig{RpcURL: stub.URL}, testRetryPolicy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(response.Errors) != 0 {
+		t.Fatalf("unexpected partial errors: %v", response.Errors)
+	}
+	if got := stub.count("getTokenAccountsByOwner"); got != 2 {
+		t.Errorf("getTokenAccountsByOwner calls = %d, want 2 (SPL Token and Token-2022)", got)
+	}
+
+	if len(response.Tokens.Data) != 1 || response.Tokens.Data[0].ChainName != solanaChainName {
+		t.Fatalf("tokens = %+v, want one sol chain", response.Tokens.Data)
+	}
+
+	want := map[string]string{
+		"":        "1.5",
+		usdcMint:  "3.5",
+		pyusdMint: "42",
+	}
+	tokens := response.Tokens.Data[0].Tokens
+	if len(tokens) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %+v", len(tokens), len(want), tokens)
+	}
+	for _, token := range tokens {
+		amount, found := want[token.ContractAddress]
+		if !found {
+			t.Errorf("unexpected token %s (%s)", token.Name, token.ContractAddress)
+			continue
+		}
+		if got := token.Amount.Text('f', -1); got != amount {
+			t.Errorf("token %s amount = %s, want %s", token.Name, got, amount)
+		}
+	}
+}
+
+func TestParseSolanaAccountPartialFailure(t *testing.T) {
+	const wallet = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+
+	stub := newRpcStub(t, func(method string, params []json.RawMessage) (interface{}, *rpcError) {
+		if method == "getBalance" {
+			return map[string]interface{}{"value": 0}, nil
+		}
+		return nil, &rpcError{Code: -32000, Message: "token accounts unavailable"}
+	})
+
+	response, err := ParseSolanaAccount(context.Background(), wallet, customTypes.SolanaConfig{RpcURL: stub.URL}, testRetryPolicy)
+	if err != nil {
+		t.Fatalf("native balance succeeded, want partial result, got error: %v", err)
+	}
+	if len(response.Errors) != 2 {
+		t.Errorf("errors = %v, want one per token program", response.Errors)
+	}
+	if PartialResultError(response) == nil {
+		t.Error("want partial result error for failed token programs")
+	}
+}
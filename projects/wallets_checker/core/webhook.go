@@ -0,0 +1,66 @@
+package core
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+)
+
+// SignWebhook считает подпись тела вебхука: HMAC-SHA256 от "<timestamp>.<body>" в hex.
+// Timestamp входит в подпись, чтобы получатель мог отбрасывать повторно отправленные старые запросы.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PostWebhook отправляет JSON body на url с повторами по policy. Запрос всегда подписывается
+// заголовками X-Webhook-Timestamp и X-Webhook-Signature, без секрета он не отправляется.
+func PostWebhook(ctx context.Context, policy RetryPolicy, url string, secret string, body []byte) error {
+	if secret == "" {
+		return fmt.Errorf("webhook secret is not set")
+	}
+	timestamp := time.Now().Unix()
+
+	return policy.Do(ctx, "webhook", func() error {
+		client, err := GetClient(nil)
+		if err != nil {
+			return err
+		}
+
+		req := fasthttp.AcquireRequest()
+		defer fasthttp.ReleaseRequest(req)
+		req.SetRequestURI(url)
+		req.Header.SetMethod(fasthttp.MethodPost)
+		req.Header.SetContentType("application/json")
+		req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+		req.SetBody(body)
+
+		resp := fasthttp.AcquireResponse()
+		defer fasthttp.ReleaseResponse(resp)
+
+		if err := doWithContext(ctx, client, req, resp); err != nil {
+			return fmt.Errorf("webhook request error: %w", err)
+		}
+
+		if resp.StatusCode() < 200 || resp.StatusCode() >= 300 {
+			return statusError(fmt.Errorf("webhook unexpected status code: %d", resp.StatusCode()),
+				resp.StatusCode(), string(resp.Header.Peek("Retry-After")))
+		}
+		return nil
+	})
+}
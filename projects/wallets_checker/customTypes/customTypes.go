@@ -35,12 +35,186 @@ type RabbyReturnData struct {
 	ChainBalance float64 `json:"chain_balance"`
 }
 
+type DebankConfig struct {
+	ParseTokens bool `json:"parse_tokens"`
+	ParseNfts   bool `json:"parse_nfts"`
+	ParsePools  bool `json:"parse_pools"`
+}
+
 type ConfigStruct struct {
-	DebankConfig struct {
-		ParseTokens bool `json:"parse_tokens"`
-		ParseNfts   bool `json:"parse_nfts"`
-		ParsePools  bool `json:"parse_pools"`
-	} `json:"debank_config"`
+	DebankConfig        DebankConfig           `json:"debank_config"`
+	DebankOpenApiConfig DebankOpenApiConfig    `json:"debank_openapi_config"`
+	RpcConfig           RpcConfig              `json:"rpc_config"`
+	SolanaConfig        SolanaConfig           `json:"solana_config"`
+	CosmosConfig        CosmosConfig           `json:"cosmos_config"`
+	ApprovalConfig      ApprovalConfig         `json:"approval_config"`
+	ActivityConfig      ActivityConfig         `json:"activity_config"`
+	EnsConfig           EnsConfig              `json:"ens_config"`
+	PriceConfig         PriceConfig            `json:"price_config"`
+	RetryConfig         map[string]RetryConfig `json:"retry_config"`
+	AlertConfig         AlertConfig            `json:"alert_config"`
+}
+
+// AlertConfig - пороги изменений между проверками базы и вебхуки, куда отправляются алерты.
+// Изменение баланса попадает в алерт, если превышен хотя бы один из порогов (процент или USD).
+type AlertConfig struct {
+	Webhooks         []WebhookConfig `json:"webhooks"`
+	MinChangePercent float64         `json:"min_change_percent"`
+	MinChangeUSD     float64         `json:"min_change_usd"`
+	// Токены и позиции дешевле этой суммы не считаются появившимися/исчезнувшими (спам и пыль)
+	MinTokenUSD float64 `json:"min_token_usd"`
+}
+
+// WebhookConfig - адрес вебхука и секрет для HMAC-SHA256 подписи тела запроса. Секрет обязателен.
+type WebhookConfig struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// RetryConfig - политика повторов запросов для одного провайдера (ключ - имя провайдера).
+type RetryConfig struct {
+	MaxAttempts int `json:"max_attempts"`
+	BaseDelayMs int `json:"base_delay_ms"`
+	MaxDelayMs  int `json:"max_delay_ms"`
+}
+
+type CoingeckoChainConfig struct {
+	Platform     string `json:"platform"`
+	NativeCoinID string `json:"native_coin_id"`
+}
+
+type CoingeckoConfig struct {
+	BaseURL      string                          `json:"base_url"`
+	ApiKey       string                          `json:"api_key,omitempty"`
+	ApiKeyHeader string                          `json:"api_key_header,omitempty"`
+	Chains       map[string]CoingeckoChainConfig `json:"chains,omitempty"`
+}
+
+// PriceConfig - источники цен запроса. Файл статических цен задается только флагом сервера -price-file,
+// чтобы клиент API не мог заставить сервер читать произвольный локальный файл.
+type PriceConfig struct {
+	Coingecko CoingeckoConfig `json:"coingecko"`
+	CacheTTL  int             `json:"cache_ttl"`
+}
+
+type PriceKey struct {
+	Chain    string
+	Contract string
+}
+
+type PriceInfo struct {
+	USD       float64 `json:"usd"`
+	Timestamp int64   `json:"timestamp"`
+	Source    string  `json:"source"`
+}
+
+type DebankOpenApiConfig struct {
+	AccessKey string `json:"access_key"`
+	BaseURL   string `json:"base_url,omitempty"`
+}
+
+type RpcTokenConfig struct {
+	Contract string `json:"contract"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+type RpcChainConfig struct {
+	RpcURL       string           `json:"rpc_url"`
+	NativeSymbol string           `json:"native_symbol"`
+	Multicall    string           `json:"multicall,omitempty"`
+	BatchSize    int              `json:"batch_size,omitempty"`
+	Tokens       []RpcTokenConfig `json:"tokens"`
+}
+
+type RpcConfig struct {
+	Chains map[string]RpcChainConfig `json:"chains"`
+}
+
+// SolanaConfig - RPC нода Solana. Локальный список токенов задается флагом сервера -solana-token-list.
+type SolanaConfig struct {
+	RpcURL string `json:"rpc_url"`
+}
+
+type CosmosDenomConfig struct {
+	Denom    string `json:"denom"`
+	Symbol   string `json:"symbol"`
+	Decimals int    `json:"decimals"`
+}
+
+// CosmosChainConfig - LCD (REST) эндпоинт сети Cosmos SDK. Для известных сетей (cosmoshub, osmosis, ...)
+// prefix и native_denom можно не указывать. Аккаунт проверяется в сетях с префиксом его адреса.
+type CosmosChainConfig struct {
+	LcdURL      string              `json:"lcd_url"`
+	Prefix      string              `json:"prefix,omitempty"`
+	NativeDenom string              `json:"native_denom,omitempty"`
+	Denoms      []CosmosDenomConfig `json:"denoms,omitempty"`
+}
+
+type CosmosConfig struct {
+	Chains map[string]CosmosChainConfig `json:"chains"`
+}
+
+// ApprovalConfig - настройки аудита approve. Логи читаются с нод из rpc_config.chains.
+type ApprovalConfig struct {
+	// Блок, с которого сканируются логи в каждой сети (по умолчанию с генезиса)
+	FromBlock map[string]uint64 `json:"from_block,omitempty"`
+	// Максимальный диапазон блоков одного eth_getLogs, 0 - весь диапазон одним запросом
+	BlockRange uint64 `json:"block_range,omitempty"`
+	// Доверенные spender: адрес -> название. Дополняют встроенный список (роутеры DEX, Permit2, Seaport)
+	KnownSpenders map[string]string `json:"known_spenders,omitempty"`
+}
+
+// TokenApproval - действующее разрешение на списание токенов кошелька.
+// Standard: erc20 (allowance), erc721 (approve одного токена) или approval_for_all (вся коллекция).
+type TokenApproval struct {
+	ChainName   string `json:"chain_name"`
+	Token       string `json:"token"`
+	TokenSymbol string `json:"token_symbol,omitempty"`
+	Standard    string `json:"standard"`
+	Spender     string `json:"spender"`
+	SpenderName string `json:"spender_name,omitempty"`
+	TokenID     string `json:"token_id,omitempty"`
+	Allowance   string `json:"allowance,omitempty"`
+	Unlimited   bool   `json:"unlimited"`
+	// Risky - безлимитное разрешение неизвестному spender
+	Risky       bool   `json:"risky"`
+	BlockNumber uint64 `json:"block_number"`
+}
+
+type ApprovalsReport struct {
+	WalletAddress string          `json:"wallet_address"`
+	Approvals     []TokenApproval `json:"approvals"`
+	RiskyCount    int             `json:"risky_count"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+// EnsConfig - mainnet RPC для reverse разрешения ENS имен проверяемых адресов.
+// Если не задан, используется RPC из флага -ens-rpc.
+type EnsConfig struct {
+	RpcURL string `json:"rpc_url"`
+}
+
+// ActivityConfig - сбор статистики активности EVM кошельков по сетям из rpc_config.chains.
+// Поиск первого и последнего блока активности требует архивной ноды.
+type ActivityConfig struct {
+	// Enabled включает сбор при каждой проверке. Цена - около 80-110 запросов к архивной ноде
+	// на кошелек в каждой сети: два бинарных поиска по ~25 блоков (на 20 млн блоков)
+	// по 1-3 запроса на шаг плюс чтение текущего состояния.
+	Enabled bool `json:"enabled"`
+}
+
+// ChainActivity - активность адреса в одной сети. FirstActivityBlock - первый блок, где у адреса
+// появились nonce, баланс или код. LastActivityBlock - блок последней исходящей транзакции,
+// то есть последнего увеличения nonce (0 - исходящих транзакций нет). Входящие переводы на него не влияют.
+type ChainActivity struct {
+	ChainName          string `json:"chain_name"`
+	Nonce              uint64 `json:"nonce"`
+	IsContract         bool   `json:"is_contract"`
+	FirstActivityBlock uint64 `json:"first_activity_block,omitempty"`
+	LastActivityBlock  uint64 `json:"last_activity_block,omitempty"`
+	UpdatedAt          int64  `json:"updated_at"`
+	Error              string `json:"error,omitempty"`
 }
 
 type TokenData struct {
@@ -48,6 +222,7 @@ type TokenData struct {
 	BalanceUSD      *big.Float `json:"balance_usd"`
 	Amount          *big.Float `json:"amount"`
 	ContractAddress string     `json:"contract_address"`
+	Price           *PriceInfo `json:"price,omitempty"`
 }
 
 type ChainTokens struct {
@@ -98,10 +273,27 @@ type PoolsData struct {
 }
 
 type ServerResponse struct {
-	WalletAddress string     `json:"wallet_address"`
-	WalletData    string     `json:"wallet_data"`
-	TotalBalance  float64    `json:"total_balance"`
-	Tokens        TokensData `json:"tokens"`
-	NFTs          NFTsData  `json:"nfts"`
-	Pools         PoolsData `json:"pools"`
+	WalletAddress string          `json:"wallet_address"`
+	WalletData    string          `json:"wallet_data"`
+	ChainFamily   string          `json:"chain_family,omitempty"`
+	EnsName       string          `json:"ens_name,omitempty"`
+	TotalBalance  float64         `json:"total_balance"`
+	Tokens        TokensData      `json:"tokens"`
+	NFTs          NFTsData        `json:"nfts"`
+	Pools         PoolsData       `json:"pools"`
+	Activity      []ChainActivity `json:"activity,omitempty"`
+	Errors        []string        `json:"errors,omitempty"`
+}
+
+type ProviderCapabilities struct {
+	Tokens bool `json:"tokens"`
+	NFTs   bool `json:"nfts"`
+	Pools  bool `json:"pools"`
+	// Семейства сетей, аккаунты которых провайдер умеет проверять (evm, solana, cosmos, ...)
+	Families []string `json:"families"`
+}
+
+type ProviderInfo struct {
+	Name         string               `json:"name"`
+	Capabilities ProviderCapabilities `json:"capabilities"`
 }
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"bufio"
 	"debank_checker_v3/core"
 	"debank_checker_v3/customTypes"
 	"debank_checker_v3/modules"
 	"debank_checker_v3/utils"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
@@ -24,127 +26,293 @@ type RequestData struct {
 	Config  customTypes.ConfigStruct `json:"config"`
 }
 
-type ResponseData struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error,omitempty"`
+// handleCheck проверяет один аккаунт. В watch-only режиме провайдеру передается только адрес,
+// поэтому исходные данные не попадают ни в ответ, ни в логи провайдеров.
+func handleCheck(store modules.Store, watchOnly bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			modules.WriteError(w, http.StatusMethodNotAllowed, modules.CodeMethodNotAllowed, fmt.Errorf("only POST method is allowed"))
+			return
+		}
+
+		var reqData RequestData
+		if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
+			modules.WriteError(w, http.StatusBadRequest, modules.CodeInvalidRequest, fmt.Errorf("error parsing request body: %v", err))
+			return
+		}
+
+		provider, err := core.GetProvider(reqData.Type)
+		if err != nil {
+			modules.WriteError(w, http.StatusBadRequest, modules.CodeUnknownProvider, err)
+			return
+		}
+
+		if reqData.Proxy, err = utils.ParseProxies(reqData.Proxy); err != nil {
+			modules.WriteError(w, http.StatusBadRequest, core.CodeInvalidConfig, err)
+			return
+		}
+
+		if watchOnly {
+			info, err := utils.GetAccountInfo(reqData.Account)
+			if err != nil {
+				modules.WriteError(w, http.StatusUnprocessableEntity, core.CodeInvalidCredentials, fmt.Errorf("wrong account credentials"))
+				return
+			}
+			reqData.Account = utils.WatchOnlyAddress(info)
+		}
+
+		// Аккаунт не из EVM сети проверяется провайдером, который поддерживает его семейство
+		if family, err := utils.GetChainFamily(reqData.Account); err == nil {
+			provider, err = core.ProviderForAccount(provider, family)
+			if err != nil {
+				modules.WriteError(w, core.HTTPStatus(err), core.ErrorCode(err), err)
+				return
+			}
+		}
+
+		result, err := provider.ParseAccount(r.Context(), reqData.Account, core.CheckOptions{
+			Proxies: reqData.Proxy,
+			Config:  reqData.Config,
+		})
+		if err != nil {
+			log.Printf("%s | Check Failed: %v", utils.RedactAccountData(reqData.Account), err)
+			modules.WriteError(w, core.HTTPStatus(err), core.ErrorCode(err), err)
+			return
+		}
+		core.EnrichResponse(r.Context(), result, reqData.Config)
+
+		// Сохраняем результаты проверки в базу данных
+		if err := store.SaveCheckResult(result); err != nil {
+			log.Printf("Error saving check results: %v", err)
+		}
+		modules.RecordHistory(store, provider.Name(), []*customTypes.ServerResponse{result})
+
+		// Неполный результат отдается со статусом 200, список ошибок лежит в result.errors
+		if partialErr := core.PartialResultError(result); partialErr != nil {
+			log.Printf("Partial Result: %v", partialErr)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
 }
 
-func handleCheck(w http.ResponseWriter, r *http.Request) {
+// handleApprovals возвращает действующие approve кошелька. Для аудита нужен только адрес,
+// поэтому секрет сразу заменяется адресом и дальше никуда не передается.
+func handleApprovals(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		modules.WriteError(w, http.StatusMethodNotAllowed, modules.CodeMethodNotAllowed, fmt.Errorf("only POST method is allowed"))
 		return
 	}
 
 	var reqData RequestData
 	if err := json.NewDecoder(r.Body).Decode(&reqData); err != nil {
-		http.Error(w, fmt.Sprintf("Error parsing request body: %v", err), http.StatusBadRequest)
+		modules.WriteError(w, http.StatusBadRequest, modules.CodeInvalidRequest, fmt.Errorf("error parsing request body: %v", err))
 		return
 	}
 
-	utils.ConfigFile = reqData.Config
-
-	var result *customTypes.ServerResponse
-	var err error
-
-	switch reqData.Type {
-	case "debank":
-		result, err = core.ParseDebankAccount(reqData.Account, reqData.Proxy)
-	case "rabby":
-		result, err = core.ParseRabbyAccount(reqData.Account, reqData.Proxy)
-	default:
-		http.Error(w, "Invalid type. Must be 'debank' or 'rabby'", http.StatusBadRequest)
+	info, err := utils.GetAccountInfo(reqData.Account)
+	if err != nil {
+		modules.WriteError(w, http.StatusUnprocessableEntity, core.CodeInvalidCredentials, fmt.Errorf("wrong account credentials"))
 		return
 	}
 
+	report, err := core.AuditApprovals(r.Context(), utils.WatchOnlyAddress(info), reqData.Config)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Printf("%s | Approvals Audit Failed: %v", info.Address, err)
+		modules.WriteError(w, core.HTTPStatus(err), core.ErrorCode(err), err)
 		return
 	}
 
-	// Сохраняем результаты проверки в базу данных
-	if err := saveCheckResults(result); err != nil {
-		log.Printf("Error saving check results: %v", err)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+func handleGetProviders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		modules.WriteError(w, http.StatusMethodNotAllowed, modules.CodeMethodNotAllowed, fmt.Errorf("only GET method is allowed"))
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(result)
+	json.NewEncoder(w).Encode(core.ListProviders())
 }
 
-func saveCheckResults(result *customTypes.ServerResponse) error {
-	const accountsPath = "data/accounts"
-	entries, err := os.ReadDir(accountsPath)
-	if err != nil {
-		return fmt.Errorf("failed to read accounts directory: %v", err)
+// openStore открывает хранилище баз: JSON файлы в data/accounts (история в data/history)
+// или файл bbolt data/accounts.db.
+func openStore(storeType string, dataDir string, fsync modules.FsyncPolicy) (modules.Store, error) {
+	switch storeType {
+	case "json":
+		return modules.NewJSONStore(dataDir, fsync)
+	case "bolt":
+		return modules.NewBoltStore(filepath.Join(dataDir, "accounts.db"), fsync)
 	}
+	return nil, fmt.Errorf("unknown store type: %s", storeType)
+}
 
-	// Ищем аккаунт во всех базах
-	for _, entry := range entries {
-		if filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
+const vaultPassphraseEnv = "VAULT_PASSPHRASE"
 
-		filePath := filepath.Join(accountsPath, entry.Name())
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			continue
-		}
-
-		var base modules.AccountsBase
-		if err := json.Unmarshal(data, &base); err != nil {
-			continue
-		}
-
-		// Ищем аккаунт в текущей базе
-		updated := false
-		for i, acc := range base.Accounts {
-			// Ищем аккаунт либо по адресу, либо по account_data
-			if strings.EqualFold(acc.Address, result.WalletAddress) || 
-			   strings.EqualFold(acc.AccountData, result.WalletData) {
-				// Обновляем данные аккаунта
-				base.Accounts[i].Address = result.WalletAddress // Сохраняем реальный адрес
-				base.Accounts[i].Balance = result.TotalBalance
-				base.Accounts[i].LastCheck = time.Now().Unix()
-				base.Accounts[i].Tokens = result.Tokens
-				base.Accounts[i].NFTs = result.NFTs
-				base.Accounts[i].Pools = result.Pools
-				updated = true
-				break
-			}
+// readPassphrase берет пароль хранилища из VAULT_PASSPHRASE или спрашивает его в консоли.
+func readPassphrase(reader *bufio.Reader, confirm bool) (string, error) {
+	if passphrase := os.Getenv(vaultPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+
+	fmt.Print("Vault passphrase: ")
+	passphrase, err := reader.ReadString('\n')
+	if err != nil && passphrase == "" {
+		return "", err
+	}
+	passphrase = strings.TrimRight(passphrase, "\r\n")
+
+	if confirm {
+		fmt.Print("Repeat passphrase: ")
+		repeated, _ := reader.ReadString('\n')
+		if strings.TrimRight(repeated, "\r\n") != passphrase {
+			return "", fmt.Errorf("passphrases do not match")
 		}
+	}
+	return passphrase, nil
+}
 
-		if updated {
-			// Сохраняем обновленную базу
-			newData, err := json.MarshalIndent(base, "", "    ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal updated base: %v", err)
-			}
+// migrateVault создает хранилище ключа при первом запуске и шифрует открытые секреты во всех базах.
+func migrateVault(store modules.Store, vault *modules.Vault) error {
+	reader := bufio.NewReader(os.Stdin)
 
-			if err := os.WriteFile(filePath, newData, 0644); err != nil {
-				return fmt.Errorf("failed to write updated base: %v", err)
-			}
-			break
+	initialized := vault.Status().Initialized
+	passphrase, err := readPassphrase(reader, !initialized)
+	if err != nil {
+		return err
+	}
+
+	if !initialized {
+		if err := vault.Initialize(passphrase); err != nil {
+			return err
 		}
+		log.Printf("Vault Initialized")
 	}
 
+	if _, err := vault.Unlock(passphrase, time.Hour); err != nil {
+		return err
+	}
+	defer vault.Lock()
+
+	migrated, err := modules.MigrateToVault(modules.NewVaultStore(store, vault))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Encrypted %d Accounts", migrated)
 	return nil
 }
 
 func main() {
 	fmt.Printf("WebSite - nazavod.dev\nAntiDrain - antidrain.me\nTG - t.me/n4z4v0d\n\n")
 
-	accountHandler := modules.NewAccountHandler()
+	storeType := flag.String("store", "json", "account bases storage: json or bolt")
+	dataDir := flag.String("data", "data", "data directory")
+	fsyncFlag := flag.String("fsync", "always", "flush base writes to disk: always or never")
+	vaultMode := flag.Bool("vault", false, "keep mnemonics and private keys encrypted, unlock via /vault/unlock")
+	migrate := flag.Bool("migrate-vault", false, "encrypt plaintext secrets in all bases and exit")
+	watchOnly := flag.Bool("watch-only", false, "store and check addresses only, secrets are discarded at import")
+	migrateWatchOnly := flag.Bool("migrate-watch-only", false, "irreversibly replace secrets in all bases with their addresses and exit")
+	ensRPC := flag.String("ens-rpc", os.Getenv("ENS_RPC_URL"), "ethereum mainnet rpc for resolving name.eth accounts and primary ens names")
+	priceFile := flag.String("price-file", "", "json file with static token prices used by rpc based providers")
+	solanaTokenList := flag.String("solana-token-list", "", `solana token list json: {"tokens": [{"address", "symbol", "name", "decimals"}]}`)
+	flag.Parse()
+
+	if err := core.SetStaticPriceFile(*priceFile); err != nil {
+		log.Fatal(err)
+	}
+	if err := core.SetSolanaTokenList(*solanaTokenList); err != nil {
+		log.Fatal(err)
+	}
+
+	core.SetEnsRPC(*ensRPC)
+
+	fsync, err := modules.ParseFsyncPolicy(*fsyncFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := openStore(*storeType, *dataDir, fsync)
+	if err != nil {
+		log.Fatalf("Failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	if *migrateWatchOnly {
+		stripped, err := modules.MigrateToWatchOnly(store)
+		if err != nil {
+			log.Fatalf("Watch-only migration failed: %v", err)
+		}
+		log.Printf("Discarded Secrets Of %d Accounts", stripped)
+		return
+	}
+
+	if *watchOnly {
+		baseName, err := modules.FindBaseWithSecrets(store)
+		if err != nil {
+			log.Fatalf("Failed to read bases: %v", err)
+		}
+		if baseName != "" {
+			log.Fatalf("Base %s contains secrets, run with -migrate-watch-only first", baseName)
+		}
+	}
+
+	var vault *modules.Vault
+	if *vaultMode || *migrate {
+		if vault, err = modules.OpenVault(filepath.Join(*dataDir, "vault.json")); err != nil {
+			log.Fatalf("Failed to open vault: %v", err)
+		}
+	}
+
+	if *migrate {
+		if err := migrateVault(store, vault); err != nil {
+			log.Fatalf("Vault migration failed: %v", err)
+		}
+		return
+	}
+
+	if *vaultMode {
+		if !vault.Status().Initialized {
+			log.Fatalf("Vault is not initialized, run with -migrate-vault first")
+		}
+		store = modules.NewVaultStore(store, vault)
+	}
+
+	accountHandler := modules.NewAccountHandler(store, *watchOnly)
+	jobHandler := modules.NewJobHandler(modules.NewJobManager(store))
 
 	// Создаем новый mux
 	mux := http.NewServeMux()
 
 	// Регистрируем обработчики на mux вместо http.DefaultServeMux
-	mux.HandleFunc("/check", handleCheck)
+	mux.HandleFunc("/check", handleCheck(store, *watchOnly))
+	mux.HandleFunc("/providers", handleGetProviders)
+	mux.HandleFunc("/approvals", handleApprovals)
 	mux.HandleFunc("/accounts/create", accountHandler.HandleCreateAccountsBase)
 	mux.HandleFunc("/accounts/all", accountHandler.HandleGetAllBases)
 	mux.HandleFunc("/accounts/delete", accountHandler.HandleDeleteBase)
 	mux.HandleFunc("/accounts/edit", accountHandler.HandleEditAccount)
 	mux.HandleFunc("/accounts/delete-one", accountHandler.HandleDeleteAccount)
 	mux.HandleFunc("/accounts/replace", accountHandler.HandleReplaceBase)
+	mux.HandleFunc("/accounts/import", accountHandler.HandleImportText)
+	mux.HandleFunc("/accounts/import/preview", accountHandler.HandleImportPreview)
+	mux.HandleFunc("GET /accounts/{address}/history", accountHandler.HandleGetHistory)
+	mux.HandleFunc("/bases/{name}/check", accountHandler.HandleCheckBase)
+	mux.HandleFunc("/bases/{name}/approvals", accountHandler.HandleBaseApprovals)
+	mux.HandleFunc("GET /bases/{name}/history/daily", accountHandler.HandleGetBaseDailyHistory)
+	if vault != nil {
+		vaultHandler := modules.NewVaultHandler(vault)
+		mux.HandleFunc("GET /vault/status", vaultHandler.HandleStatus)
+		mux.HandleFunc("POST /vault/unlock", vaultHandler.HandleUnlock)
+		mux.HandleFunc("POST /vault/lock", vaultHandler.HandleLock)
+	}
+	mux.HandleFunc("POST /jobs", jobHandler.HandleCreateJob)
+	mux.HandleFunc("GET /jobs", jobHandler.HandleListJobs)
+	mux.HandleFunc("GET /jobs/{id}", jobHandler.HandleGetJob)
+	mux.HandleFunc("DELETE /jobs/{id}", jobHandler.HandleCancelJob)
+	mux.HandleFunc("GET /jobs/{id}/events", jobHandler.HandleJobEvents)
 
 	// Настраиваем CORS
 	corsHandler := cors.New(cors.Options{
@@ -2,15 +2,18 @@ package modules
 
 import (
 	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 )
 
 type AccountsBase struct {
 	AccountsName string        `json:"accounts_name"`
 	Accounts     []AccountData `json:"accounts"`
+	// WatchOnly - в базе хранятся только адреса, секреты отбрасываются при импорте
+	WatchOnly bool `json:"watch_only,omitempty"`
 }
 
 type AccountData struct {
@@ -19,7 +22,20 @@ type AccountData struct {
 	Balance     float64  `json:"balance"`
 	Proxy       []string `json:"proxy"`
 	LastCheck   int64    `json:"last_check"`
-	
+	// ChainFamily - семейство сети (evm, solana, cosmos, ...), по нему выбирается провайдер проверки
+	ChainFamily string `json:"chain_family,omitempty"`
+	// EnsName - основное ENS имя адреса или имя, по которому аккаунт был импортирован
+	EnsName string `json:"ens_name,omitempty"`
+
+	// Заданы для аккаунтов, выведенных из мнемоники при импорте с настройкой derivation.
+	// SeedFingerprint одинаков у всех аккаунтов одного сида и связывает их между собой.
+	DerivationPath  string `json:"derivation_path,omitempty"`
+	SeedFingerprint string `json:"seed_fingerprint,omitempty"`
+	Passphrase      string `json:"passphrase,omitempty"` // BIP39 пароль сида, принимается только с -vault
+
+	// Статистика активности по сетям, собирается при activity_config.enabled
+	Activity []customTypes.ChainActivity `json:"activity,omitempty"`
+
 	// Используем те же структуры, что и в ServerResponse
 	Tokens customTypes.TokensData `json:"tokens"`
 	NFTs   customTypes.NFTsData  `json:"nfts"`
@@ -35,6 +51,8 @@ type InputAccountData struct {
 type CreateBaseRequest struct {
 	AccountsName string            `json:"accounts_name"`
 	Accounts     []InputAccountData `json:"accounts"`
+	WatchOnly    bool               `json:"watch_only"`
+	Derivation   *DerivationConfig  `json:"derivation,omitempty"`
 }
 
 type EditAccountRequest struct {
@@ -49,22 +67,26 @@ type DeleteAccountRequest struct {
 }
 
 type ReplaceBaseRequest struct {
-	BaseName string            `json:"base_name"`
-	Accounts []InputAccountData `json:"accounts"`
+	BaseName  string            `json:"base_name"`
+	Accounts   []InputAccountData `json:"accounts"`
+	WatchOnly  bool               `json:"watch_only"`
+	Derivation *DerivationConfig  `json:"derivation,omitempty"`
 }
 
-const accountsPath = "data/accounts"
-
-func init() {
-	if err := os.MkdirAll(accountsPath, 0755); err != nil {
-		panic(err)
-	}
+type AccountHandler struct {
+	store Store
+	// watchOnly включает watch-only для всех баз сразу
+	watchOnly bool
 }
 
-type AccountHandler struct{}
+func NewAccountHandler(store Store, watchOnly bool) *AccountHandler {
+	return &AccountHandler{store: store, watchOnly: watchOnly}
+}
 
-func NewAccountHandler() *AccountHandler {
-	return &AccountHandler{}
+// encrypted проверяет, что секреты баз шифруются (сервер запущен с -vault).
+func (h *AccountHandler) encrypted() bool {
+	_, isVault := h.store.(*VaultStore)
+	return isVault
 }
 
 func (h *AccountHandler) HandleCreateAccountsBase(w http.ResponseWriter, r *http.Request) {
@@ -79,44 +101,28 @@ func (h *AccountHandler) HandleCreateAccountsBase(w http.ResponseWriter, r *http
 		return
 	}
 
+	watchOnly := h.watchOnly || req.WatchOnly
+
+	// Заполняем данные для каждого аккаунта, мнемоники разворачиваются в несколько адресов
+	accounts, err := importAccounts(req.Accounts, req.Derivation, watchOnly, h.encrypted())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Создаем полную структуру базы
 	base := AccountsBase{
 		AccountsName: req.AccountsName,
-		Accounts:     make([]AccountData, len(req.Accounts)),
+		Accounts:     accounts,
+		WatchOnly:    watchOnly,
 	}
 
-	// Заполняем данные для каждого аккаунта
-	for i, inputAcc := range req.Accounts {
-		base.Accounts[i] = AccountData{
-			AccountData: inputAcc.AccountData,
-			Address:    inputAcc.AccountData, // Используем account_data как адрес
-			Proxy:      inputAcc.Proxy,
-			Balance:    0,
-			LastCheck:  0,
-			Tokens: customTypes.TokensData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainTokens, 0),
-			},
-			NFTs: customTypes.NFTsData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainNfts, 0),
-			},
-			Pools: customTypes.PoolsData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainPools, 0),
-			},
+	if err := h.store.SaveBase(base); err != nil {
+		if errors.Is(err, ErrVaultLocked) {
+			http.Error(w, "Vault is locked", http.StatusLocked)
+			return
 		}
-	}
-
-	filePath := filepath.Join(accountsPath, base.AccountsName+".json")
-	data, err := json.MarshalIndent(base, "", "    ")
-	if err != nil {
-		http.Error(w, "Failed to marshal data", http.StatusInternalServerError)
-		return
-	}
-
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
+		http.Error(w, "Failed to save base", http.StatusInternalServerError)
 		return
 	}
 
@@ -129,30 +135,12 @@ func (h *AccountHandler) HandleGetAllBases(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	entries, err := os.ReadDir(accountsPath)
+	bases, err := h.store.ListBases()
 	if err != nil {
-		http.Error(w, "Failed to read directory", http.StatusInternalServerError)
+		http.Error(w, "Failed to read bases", http.StatusInternalServerError)
 		return
 	}
 
-	var bases []AccountsBase
-	for _, entry := range entries {
-		if filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		data, err := os.ReadFile(filepath.Join(accountsPath, entry.Name()))
-		if err != nil {
-			continue
-		}
-
-		var base AccountsBase
-		if err := json.Unmarshal(data, &base); err != nil {
-			continue
-		}
-		bases = append(bases, base)
-	}
-
 	json.NewEncoder(w).Encode(bases)
 }
 
@@ -168,15 +156,32 @@ func (h *AccountHandler) HandleDeleteBase(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	filePath := filepath.Join(accountsPath, baseName+".json")
-	if err := os.Remove(filePath); err != nil {
-		http.Error(w, "Failed to delete file", http.StatusInternalServerError)
+	if err := h.store.DeleteBase(baseName); err != nil {
+		if errors.Is(err, ErrBaseNotFound) {
+			http.Error(w, "Base not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete base", http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// writeStoreError отвечает на ошибку изменения аккаунта в базе.
+func writeStoreError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrBaseNotFound):
+		http.Error(w, "Base not found", http.StatusNotFound)
+	case errors.Is(err, ErrAccountNotFound):
+		http.Error(w, "Invalid account index", http.StatusBadRequest)
+	case errors.Is(err, ErrVaultLocked):
+		http.Error(w, "Vault is locked", http.StatusLocked)
+	default:
+		http.Error(w, "Failed to update base", http.StatusInternalServerError)
+	}
+}
+
 func (h *AccountHandler) HandleEditAccount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPut {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -189,45 +194,50 @@ func (h *AccountHandler) HandleEditAccount(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	filePath := filepath.Join(accountsPath, req.BaseName+".json")
-	data, err := os.ReadFile(filePath)
+	proxies, err := utils.ParseProxies(req.AccountData.Proxy)
 	if err != nil {
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	req.AccountData.Proxy = proxies
 
-	var base AccountsBase
-	if err := json.Unmarshal(data, &base); err != nil {
-		http.Error(w, "Failed to parse file", http.StatusInternalServerError)
-		return
+	watchOnly := h.watchOnly
+	if !watchOnly {
+		base, err := h.store.GetBase(req.BaseName)
+		if err != nil {
+			writeStoreError(w, err)
+			return
+		}
+		watchOnly = base.WatchOnly
 	}
 
-	if req.Index >= len(base.Accounts) {
-		http.Error(w, "Invalid account index", http.StatusBadRequest)
-		return
+	if watchOnly {
+		account, err := watchOnlyAccount(req.AccountData)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req.AccountData = account
 	}
 
 	// Обновляем только account_data и proxy, сохраняем остальные данные
-	oldAccount := base.Accounts[req.Index]
-	base.Accounts[req.Index] = AccountData{
-		AccountData: req.AccountData.AccountData,
-		Address:    req.AccountData.AccountData,
-		Proxy:      req.AccountData.Proxy,
-		Balance:    oldAccount.Balance,
-		LastCheck:  oldAccount.LastCheck,
-		Tokens:     oldAccount.Tokens,
-		NFTs:       oldAccount.NFTs,
-		Pools:      oldAccount.Pools,
-	}
-
-	newData, err := json.MarshalIndent(base, "", "    ")
+	err = h.store.UpdateAccount(req.BaseName, req.Index, func(account *AccountData) {
+		account.AccountData = req.AccountData.AccountData
+		account.Address = req.AccountData.AccountData
+		account.Proxy = req.AccountData.Proxy
+		account.ChainFamily, _ = utils.GetChainFamily(req.AccountData.AccountData)
+		// ENS имя старого адреса к новому не относится
+		account.EnsName = ""
+		if utils.IsEnsName(req.AccountData.AccountData) {
+			account.EnsName = strings.ToLower(req.AccountData.AccountData)
+		}
+		// Новые данные - уже другой аккаунт, связь с сидом сбрасывается
+		account.DerivationPath = ""
+		account.SeedFingerprint = ""
+		account.Passphrase = ""
+	})
 	if err != nil {
-		http.Error(w, "Failed to marshal data", http.StatusInternalServerError)
-		return
-	}
-
-	if err := os.WriteFile(filePath, newData, 0644); err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
+		writeStoreError(w, err)
 		return
 	}
 
@@ -246,35 +256,8 @@ func (h *AccountHandler) HandleDeleteAccount(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	filePath := filepath.Join(accountsPath, req.BaseName+".json")
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		http.Error(w, "Failed to read file", http.StatusInternalServerError)
-		return
-	}
-
-	var base AccountsBase
-	if err := json.Unmarshal(data, &base); err != nil {
-		http.Error(w, "Failed to parse file", http.StatusInternalServerError)
-		return
-	}
-
-	if req.Index >= len(base.Accounts) {
-		http.Error(w, "Invalid account index", http.StatusBadRequest)
-		return
-	}
-
-	// Удаляем аккаунт по индексу
-	base.Accounts = append(base.Accounts[:req.Index], base.Accounts[req.Index+1:]...)
-
-	newData, err := json.MarshalIndent(base, "", "    ")
-	if err != nil {
-		http.Error(w, "Failed to marshal data", http.StatusInternalServerError)
-		return
-	}
-
-	if err := os.WriteFile(filePath, newData, 0644); err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
+	if err := h.store.DeleteAccount(req.BaseName, req.Index); err != nil {
+		writeStoreError(w, err)
 		return
 	}
 
@@ -293,50 +276,38 @@ func (h *AccountHandler) HandleReplaceBase(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Создаем новую базу с полными данными
-	newBase := AccountsBase{
-		AccountsName: req.BaseName,
-		Accounts:     make([]AccountData, len(req.Accounts)),
-	}
-
-	// Заполняем данные для каждого аккаунта
-	for i, inputAcc := range req.Accounts {
-		newBase.Accounts[i] = AccountData{
-			AccountData: inputAcc.AccountData,
-			Address:    inputAcc.AccountData,
-			Proxy:      inputAcc.Proxy,
-			Balance:    0,
-			LastCheck:  0,
-			Tokens: customTypes.TokensData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainTokens, 0),
-			},
-			NFTs: customTypes.NFTsData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainNfts, 0),
-			},
-			Pools: customTypes.PoolsData{
-				Quantity: 0,
-				Data:     make([]customTypes.ChainPools, 0),
-			},
+	existing, err := h.store.GetBase(req.BaseName)
+	if err != nil {
+		if errors.Is(err, ErrBaseNotFound) {
+			http.Error(w, "Base not found", http.StatusNotFound)
+			return
 		}
-	}
-
-	filePath := filepath.Join(accountsPath, req.BaseName+".json")
-	
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		http.Error(w, "Base not found", http.StatusNotFound)
+		http.Error(w, "Failed to read base", http.StatusInternalServerError)
 		return
 	}
 
-	data, err := json.MarshalIndent(newBase, "", "    ")
+	// Watch-only базу нельзя вернуть в обычный режим заменой аккаунтов
+	watchOnly := h.watchOnly || existing.WatchOnly || req.WatchOnly
+
+	accounts, err := importAccounts(req.Accounts, req.Derivation, watchOnly, h.encrypted())
 	if err != nil {
-		http.Error(w, "Failed to marshal data", http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := os.WriteFile(filePath, data, 0644); err != nil {
-		http.Error(w, "Failed to write file", http.StatusInternalServerError)
+	// Создаем новую базу с полными данными
+	newBase := AccountsBase{
+		AccountsName: req.BaseName,
+		Accounts:     accounts,
+		WatchOnly:    watchOnly,
+	}
+
+	if err := h.store.SaveBase(newBase); err != nil {
+		if errors.Is(err, ErrVaultLocked) {
+			http.Error(w, "Vault is locked", http.StatusLocked)
+			return
+		}
+		http.Error(w, "Failed to save base", http.StatusInternalServerError)
 		return
 	}
 
@@ -0,0 +1,95 @@
+package modules
+
+import (
+	"context"
+	"debank_checker_v3/core"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type BaseApprovalsRequest struct {
+	Config customTypes.ConfigStruct `json:"config"`
+}
+
+type BaseApprovalsSummary struct {
+	BaseName   string                        `json:"base_name"`
+	Audited    int                           `json:"audited"`
+	RiskyCount int                           `json:"risky_count"`
+	Reports    []customTypes.ApprovalsReport `json:"reports"`
+	Errors     []CheckBaseError              `json:"errors,omitempty"`
+}
+
+// approvalTarget возвращает адрес для аудита approve. Секрет для этого не нужен, поэтому
+// сохраненный адрес берется даже у аккаунтов, зашифрованных в vault.
+func approvalTarget(account AccountData) string {
+	if account.ChainFamily == utils.FamilyEVM && utils.IsPublicAddress(account.Address) {
+		return account.Address
+	}
+	return account.checkTarget()
+}
+
+// AuditBaseApprovals проверяет approve всех EVM аккаунтов базы. Аккаунты других сетей пропускаются.
+func AuditBaseApprovals(ctx context.Context, store Store, baseName string, config customTypes.ConfigStruct) (*BaseApprovalsSummary, error) {
+	base, err := store.GetBase(baseName)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &BaseApprovalsSummary{BaseName: baseName, Reports: make([]customTypes.ApprovalsReport, 0)}
+	for i, account := range base.Accounts {
+		if family, err := accountFamily(account); err == nil && family != utils.FamilyEVM {
+			continue
+		}
+
+		target := approvalTarget(account)
+		report, err := core.AuditApprovals(ctx, target, config)
+		if err != nil {
+			if ctx.Err() != nil {
+				return summary, ctx.Err()
+			}
+			summary.Errors = append(summary.Errors, CheckBaseError{
+				Index:   i,
+				Address: utils.RedactAccountData(target),
+				Error:   err.Error(),
+				Code:    core.ErrorCode(err),
+			})
+			continue
+		}
+
+		summary.Audited++
+		summary.RiskyCount += report.RiskyCount
+		summary.Reports = append(summary.Reports, *report)
+	}
+
+	log.Printf("%s | Approvals Audited: %d | Risky: %d | Failed: %d", baseName, summary.Audited, summary.RiskyCount, len(summary.Errors))
+	return summary, nil
+}
+
+func (h *AccountHandler) HandleBaseApprovals(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	baseName := strings.TrimSpace(r.PathValue("name"))
+
+	var req BaseApprovalsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidRequest(w, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	summary, err := AuditBaseApprovals(r.Context(), h.store, baseName, req.Config)
+	if err != nil {
+		writeClassifiedError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,373 @@
+package modules
+
+import (
+	"context"
+	"debank_checker_v3/core"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCheckWorkers = 5
+	maxCheckWorkers     = 50
+	// Сколько кошельков отдается за раз провайдеру, который умеет проверять пачками
+	batchProviderChunkSize = 100
+)
+
+type CheckBaseRequest struct {
+	Type    string                   `json:"type"`
+	Proxy   []string                 `json:"proxy"`
+	Config  customTypes.ConfigStruct `json:"config"`
+	Workers int                      `json:"workers"`
+}
+
+type CheckBaseError struct {
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+}
+
+type CheckBaseSummary struct {
+	BaseName string           `json:"base_name"`
+	Total    int              `json:"total"`
+	Checked  int              `json:"checked"`
+	Failed   int              `json:"failed"`
+	TotalUSD float64          `json:"total_usd"`
+	Errors   []CheckBaseError `json:"errors,omitempty"`
+	// UnitsConsumed - расход units платного API за всю проверку (только для провайдеров с учетом units)
+	UnitsConsumed float64 `json:"units_consumed,omitempty"`
+	// Изменения относительно предыдущей проверки, превысившие пороги alert_config
+	Changes []WalletDiff `json:"changes,omitempty"`
+}
+
+// ApplyCheckResult переносит результат проверки в сохраненный аккаунт.
+func ApplyCheckResult(account *AccountData, result *customTypes.ServerResponse) {
+	account.Address = result.WalletAddress // Сохраняем реальный адрес
+	account.Balance = result.TotalBalance
+	account.LastCheck = time.Now().Unix()
+	account.Tokens = result.Tokens
+	account.NFTs = result.NFTs
+	account.Pools = result.Pools
+	if result.ChainFamily != "" {
+		account.ChainFamily = result.ChainFamily
+	}
+	if result.Activity != nil {
+		account.Activity = result.Activity
+	}
+	// Имя, по которому аккаунт импортирован, остается, пока у адреса нет основного имени
+	if result.EnsName != "" {
+		account.EnsName = result.EnsName
+	}
+}
+
+type checkChunk struct {
+	provider core.BalanceProvider
+	indexes  []int
+}
+
+// CheckResultFunc вызывается из воркеров после проверки каждого аккаунта, поэтому должна быть потокобезопасной.
+// address - сохраненный адрес проверенного аккаунта (у непроверенного аккаунта там может лежать account_data).
+type CheckResultFunc func(index int, address string, result *customTypes.ServerResponse, err error)
+
+// accountFamily возвращает семейство сети аккаунта: сохраненное при импорте или определенное по данным.
+func accountFamily(account AccountData) (string, error) {
+	if account.ChainFamily != "" {
+		return account.ChainFamily, nil
+	}
+	return utils.GetChainFamily(account.checkTarget())
+}
+
+// routeAccounts разбивает аккаунты на пачки по провайдерам. Аккаунты, которые provider не
+// поддерживает, уходят в другой провайдер их семейства; если такого нет - сразу получают ошибку.
+// Аккаунты с нераспознанными данными остаются у provider, чтобы он вернул ошибку credentials.
+func routeAccounts(provider core.BalanceProvider, accounts []AccountData, errs []error) []checkChunk {
+	var order []core.BalanceProvider
+	groups := make(map[core.BalanceProvider][]int)
+
+	for index, account := range accounts {
+		accountProvider := provider
+		if family, err := accountFamily(account); err == nil {
+			accountProvider, err = core.ProviderForAccount(provider, family)
+			if err != nil {
+				errs[index] = err
+				continue
+			}
+		}
+
+		if _, exists := groups[accountProvider]; !exists {
+			order = append(order, accountProvider)
+		}
+		groups[accountProvider] = append(groups[accountProvider], index)
+	}
+
+	var chunks []checkChunk
+	for _, chunkProvider := range order {
+		chunkSize := 1
+		if _, isBatch := chunkProvider.(core.BatchBalanceProvider); isBatch {
+			chunkSize = batchProviderChunkSize
+		}
+
+		indexes := groups[chunkProvider]
+		for start := 0; start < len(indexes); start += chunkSize {
+			chunks = append(chunks, checkChunk{
+				provider: chunkProvider,
+				indexes:  indexes[start:min(start+chunkSize, len(indexes))],
+			})
+		}
+	}
+	return chunks
+}
+
+// checkAccounts прогоняет аккаунты через провайдеров пулом из workers горутин.
+// Каждый аккаунт проверяется провайдером, поддерживающим его семейство сетей (предпочтительно provider).
+// Если провайдер умеет проверять пачками, каждому воркеру отдается пачка кошельков.
+// После отмены ctx новые аккаунты не берутся в работу и получают ошибку контекста.
+func checkAccounts(ctx context.Context, provider core.BalanceProvider, accounts []AccountData, opts core.CheckOptions, workers int, onResult CheckResultFunc) ([]*customTypes.ServerResponse, []error) {
+	results := make([]*customTypes.ServerResponse, len(accounts))
+	errs := make([]error, len(accounts))
+
+	plan := routeAccounts(provider, accounts, errs)
+	if onResult != nil {
+		for index, err := range errs {
+			if err != nil {
+				onResult(index, accounts[index].Address, nil, err)
+			}
+		}
+	}
+
+	chunks := make(chan checkChunk)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for chunk := range chunks {
+				// Для пачки берем прокси первого аккаунта: batch провайдеры ходят в свои ноды, а не в web API
+				chunkOpts := opts
+				if proxies := accounts[chunk.indexes[0]].Proxy; len(proxies) > 0 {
+					chunkOpts.Proxies = proxies
+				}
+
+				if batchProvider, isBatch := chunk.provider.(core.BatchBalanceProvider); isBatch {
+					accountsData := make([]string, len(chunk.indexes))
+					for i, index := range chunk.indexes {
+						accountsData[i] = accounts[index].checkTarget()
+					}
+
+					chunkResults, chunkErrs := batchProvider.ParseAccounts(ctx, accountsData, chunkOpts)
+					for i, index := range chunk.indexes {
+						results[index], errs[index] = chunkResults[i], chunkErrs[i]
+						core.EnrichResponse(ctx, results[index], opts.Config)
+						if onResult != nil {
+							onResult(index, accounts[index].Address, results[index], errs[index])
+						}
+					}
+					continue
+				}
+
+				index := chunk.indexes[0]
+				results[index], errs[index] = chunk.provider.ParseAccount(ctx, accounts[index].checkTarget(), chunkOpts)
+				core.EnrichResponse(ctx, results[index], opts.Config)
+				if onResult != nil {
+					onResult(index, accounts[index].Address, results[index], errs[index])
+				}
+			}
+		}()
+	}
+
+	dispatched := 0
+dispatch:
+	for _, chunk := range plan {
+		select {
+		case chunks <- chunk:
+			dispatched++
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	for _, chunk := range plan[dispatched:] {
+		for _, index := range chunk.indexes {
+			errs[index] = ctx.Err()
+		}
+	}
+
+	return results, errs
+}
+
+// CheckBase проверяет все аккаунты базы и сохраняет результаты одной записью в хранилище.
+// При отмене ctx сохраняются уже полученные результаты, а вместе со сводкой возвращается ошибка контекста.
+func CheckBase(ctx context.Context, store Store, baseName string, req CheckBaseRequest, onResult CheckResultFunc) (*CheckBaseSummary, error) {
+	provider, err := core.GetProvider(req.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	base, err := store.GetBase(baseName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Секреты остались зашифрованными - хранилище заблокировано
+	for _, account := range base.Accounts {
+		if IsVaultValue(account.checkData()) {
+			return nil, ErrVaultLocked
+		}
+	}
+
+	workers := req.Workers
+	if workers <= 0 {
+		workers = defaultCheckWorkers
+	}
+	workers = min(workers, maxCheckWorkers)
+
+	opts := core.CheckOptions{Proxies: req.Proxy, Config: req.Config}
+	metered, isMetered := provider.(core.UnitsMeteredProvider)
+	var unitsBefore float64
+	var unitsErr error
+	if isMetered {
+		unitsBefore, unitsErr = metered.UnitsBalance(ctx, opts)
+	}
+
+	log.Printf("%s | Checking %d Accounts With %s (%d workers)", baseName, len(base.Accounts), provider.Name(), workers)
+	results, errs := checkAccounts(ctx, provider, base.Accounts, opts, workers, onResult)
+
+	summary := &CheckBaseSummary{
+		BaseName: baseName,
+		Total:    len(base.Accounts),
+	}
+
+	if isMetered && unitsErr == nil {
+		var unitsAfter float64
+		// Остаток читается и после отмены: units за уже выполненные запросы все равно списаны
+		if unitsAfter, unitsErr = metered.UnitsBalance(context.WithoutCancel(ctx), opts); unitsErr == nil {
+			summary.UnitsConsumed = unitsBefore - unitsAfter
+			log.Printf("%s | %s Units Consumed: %.0f", baseName, provider.Name(), summary.UnitsConsumed)
+		}
+	}
+	if unitsErr != nil {
+		log.Printf("%s | Failed To Get Units Balance: %v", baseName, unitsErr)
+	}
+
+	checked := make([]*customTypes.ServerResponse, len(results))
+
+	for i, result := range results {
+		if errs[i] != nil || result == nil {
+			summary.Failed++
+			errText, code := "empty result", core.CodeInternal
+			if errs[i] != nil {
+				errText, code = errs[i].Error(), core.ErrorCode(errs[i])
+			}
+			// У непроверенного аккаунта в Address еще лежит account_data, секрет в сводку попасть не должен
+			summary.Errors = append(summary.Errors, CheckBaseError{
+				Index:   i,
+				Address: utils.RedactAccountData(base.Accounts[i].Address),
+				Error:   errText,
+				Code:    code,
+			})
+			continue
+		}
+
+		checked[i] = result
+		summary.Checked++
+		summary.TotalUSD += result.TotalBalance
+
+		// Сравниваем со снимком, прочитанным до проверки, пока он не перезаписан
+		if diff := DiffAccount(i, base.Accounts[i], result, req.Config); diff != nil {
+			summary.Changes = append(summary.Changes, *diff)
+		}
+	}
+
+	if summary.Checked > 0 {
+		if err := store.SaveCheckResults(baseName, checked); err != nil {
+			return nil, fmt.Errorf("failed to write updated base: %v", err)
+		}
+		RecordHistory(store, provider.Name(), checked)
+		SendAlerts(baseName, summary.Changes, req.Config.AlertConfig)
+	}
+
+	log.Printf("%s | Checked: %d | Failed: %d | Changed: %d | Total: %f $", baseName, summary.Checked, summary.Failed, len(summary.Changes), summary.TotalUSD)
+	return summary, ctx.Err()
+}
+
+// validateCheckBaseRequest проверяет базу и провайдера и возвращает HTTP статус и код ошибки для ответа.
+func validateCheckBaseRequest(store Store, baseName string, req CheckBaseRequest) (int, string, error) {
+	if baseName == "" {
+		return http.StatusBadRequest, CodeInvalidRequest, fmt.Errorf("base name is required")
+	}
+
+	if _, err := core.GetProvider(req.Type); err != nil {
+		return http.StatusBadRequest, CodeUnknownProvider, err
+	}
+
+	if err := ValidateAlertConfig(req.Config.AlertConfig); err != nil {
+		return http.StatusBadRequest, core.CodeInvalidConfig, err
+	}
+
+	if _, err := utils.ParseProxies(req.Proxy); err != nil {
+		return http.StatusBadRequest, core.CodeInvalidConfig, err
+	}
+
+	exists, err := store.BaseExists(baseName)
+	if err != nil {
+		return http.StatusInternalServerError, core.CodeInternal, err
+	}
+	if !exists {
+		return http.StatusNotFound, CodeNotFound, ErrBaseNotFound
+	}
+
+	if vaultStore, ok := store.(*VaultStore); ok && vaultStore.Locked() {
+		base, err := store.GetBase(baseName)
+		if err != nil {
+			return http.StatusInternalServerError, core.CodeInternal, err
+		}
+		for _, account := range base.Accounts {
+			if IsVaultValue(account.checkData()) {
+				return http.StatusLocked, CodeVaultLocked, ErrVaultLocked
+			}
+		}
+	}
+
+	return http.StatusOK, "", nil
+}
+
+func (h *AccountHandler) HandleCheckBase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	baseName := strings.TrimSpace(r.PathValue("name"))
+
+	var req CheckBaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidRequest(w, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	if status, code, err := validateCheckBaseRequest(h.store, baseName, req); err != nil {
+		WriteError(w, status, code, err)
+		return
+	}
+
+	summary, err := CheckBase(r.Context(), h.store, baseName, req, nil)
+	if err != nil {
+		writeClassifiedError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,433 @@
+package modules
+
+import (
+	"bytes"
+	"debank_checker_v3/customTypes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Структура файла:
+//
+//	bases/<имя базы>/accounts/<seq> -> AccountData (JSON)
+//	bases/<имя базы>/meta -> настройки базы (JSON)
+//	history/<адрес>/<timestamp><seq> -> HistoryEntry (JSON)
+//
+// Каждый аккаунт лежит отдельным ключом, поэтому обновление баланса перезаписывает
+// только этот аккаунт, а не всю базу. Порядок аккаунтов задается возрастающим seq.
+// Ключи истории начинаются с timestamp, поэтому выборка за период - это один проход курсора.
+var (
+	basesBucket    = []byte("bases")
+	accountsBucket = []byte("accounts")
+	historyBucket  = []byte("history")
+	baseMetaKey    = []byte("meta")
+)
+
+// baseMeta - поля AccountsBase, кроме имени и аккаунтов.
+type baseMeta struct {
+	WatchOnly bool `json:"watch_only,omitempty"`
+}
+
+// BoltStore хранит базы во встроенной базе данных bbolt (один файл, без cgo).
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string, fsync FsyncPolicy) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+	// Транзакции bbolt атомарны в любом режиме, fsync влияет только на устойчивость к отключению питания
+	db.NoSync = fsync == FsyncNever
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{basesBucket, historyBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+func seqKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// readBase читает аккаунты и настройки базы.
+func readBase(tx *bolt.Tx, name string) (AccountsBase, error) {
+	base := AccountsBase{AccountsName: name}
+
+	accounts, err := getAccountsBucket(tx, name)
+	if err != nil {
+		return base, err
+	}
+
+	if base.Accounts, err = readAccounts(accounts); err != nil {
+		return base, fmt.Errorf("failed to parse base %s: %v", name, err)
+	}
+
+	var meta baseMeta
+	if data := tx.Bucket(basesBucket).Bucket([]byte(name)).Get(baseMetaKey); data != nil {
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return base, fmt.Errorf("failed to parse base %s: %v", name, err)
+		}
+	}
+	base.WatchOnly = meta.WatchOnly
+	return base, nil
+}
+
+func getAccountsBucket(tx *bolt.Tx, baseName string) (*bolt.Bucket, error) {
+	baseBucket := tx.Bucket(basesBucket).Bucket([]byte(baseName))
+	if baseBucket == nil {
+		return nil, ErrBaseNotFound
+	}
+	return baseBucket.Bucket(accountsBucket), nil
+}
+
+// accountKeyAt возвращает ключ аккаунта, стоящего index-м по порядку.
+func accountKeyAt(accounts *bolt.Bucket, index int) []byte {
+	if index < 0 {
+		return nil
+	}
+
+	cursor := accounts.Cursor()
+	i := 0
+	for key, _ := cursor.First(); key != nil; key, _ = cursor.Next() {
+		if i == index {
+			return bytes.Clone(key)
+		}
+		i++
+	}
+	return nil
+}
+
+func putAccount(accounts *bolt.Bucket, key []byte, account AccountData) error {
+	data, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("failed to marshal account: %v", err)
+	}
+	return accounts.Put(key, data)
+}
+
+func readAccounts(accounts *bolt.Bucket) ([]AccountData, error) {
+	result := make([]AccountData, 0, accounts.Stats().KeyN)
+	err := accounts.ForEach(func(_, value []byte) error {
+		var account AccountData
+		if err := json.Unmarshal(value, &account); err != nil {
+			return err
+		}
+		result = append(result, account)
+		return nil
+	})
+	return result, err
+}
+
+func (s *BoltStore) ListBases() ([]AccountsBase, error) {
+	var bases []AccountsBase
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(basesBucket).ForEachBucket(func(name []byte) error {
+			base, err := readBase(tx, string(name))
+			if err != nil {
+				return err
+			}
+			bases = append(bases, base)
+			return nil
+		})
+	})
+	return bases, err
+}
+
+func (s *BoltStore) GetBase(name string) (AccountsBase, error) {
+	var base AccountsBase
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		base, err = readBase(tx, name)
+		return err
+	})
+	return base, err
+}
+
+func (s *BoltStore) BaseExists(name string) (bool, error) {
+	exists := false
+	err := s.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(basesBucket).Bucket([]byte(name)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+func (s *BoltStore) SaveBase(base AccountsBase) error {
+	if base.AccountsName == "" {
+		return fmt.Errorf("base name is required")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bases := tx.Bucket(basesBucket)
+		name := []byte(base.AccountsName)
+
+		if bases.Bucket(name) != nil {
+			if err := bases.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+
+		baseBucket, err := bases.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+
+		accounts, err := baseBucket.CreateBucket(accountsBucket)
+		if err != nil {
+			return err
+		}
+
+		meta, err := json.Marshal(baseMeta{WatchOnly: base.WatchOnly})
+		if err != nil {
+			return err
+		}
+		if err := baseBucket.Put(baseMetaKey, meta); err != nil {
+			return err
+		}
+
+		for _, account := range base.Accounts {
+			seq, err := accounts.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := putAccount(accounts, seqKey(seq), account); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) DeleteBase(name string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bases := tx.Bucket(basesBucket)
+		if bases.Bucket([]byte(name)) == nil {
+			return ErrBaseNotFound
+		}
+		return bases.DeleteBucket([]byte(name))
+	})
+}
+
+func (s *BoltStore) UpdateAccount(baseName string, index int, update func(account *AccountData)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts, err := getAccountsBucket(tx, baseName)
+		if err != nil {
+			return err
+		}
+
+		key := accountKeyAt(accounts, index)
+		if key == nil {
+			return ErrAccountNotFound
+		}
+
+		var account AccountData
+		if err := json.Unmarshal(accounts.Get(key), &account); err != nil {
+			return fmt.Errorf("failed to parse account: %v", err)
+		}
+
+		update(&account)
+		return putAccount(accounts, key, account)
+	})
+}
+
+func (s *BoltStore) DeleteAccount(baseName string, index int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts, err := getAccountsBucket(tx, baseName)
+		if err != nil {
+			return err
+		}
+
+		key := accountKeyAt(accounts, index)
+		if key == nil {
+			return ErrAccountNotFound
+		}
+		return accounts.Delete(key)
+	})
+}
+
+func (s *BoltStore) SaveCheckResult(result *customTypes.ServerResponse) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		var found *bolt.Bucket
+		var foundKey []byte
+
+		// Ищем аккаунт во всех базах. Для поиска достаточно адреса и account_data,
+		// поэтому балансы и токены здесь не разбираются.
+		err := tx.Bucket(basesBucket).ForEachBucket(func(name []byte) error {
+			if found != nil {
+				return nil
+			}
+
+			accounts, err := getAccountsBucket(tx, string(name))
+			if err != nil {
+				return err
+			}
+
+			cursor := accounts.Cursor()
+			for key, value := cursor.First(); key != nil; key, value = cursor.Next() {
+				var account AccountData
+				if err := json.Unmarshal(value, &struct {
+					AccountData    *string `json:"account_data"`
+					Address        *string `json:"address"`
+					DerivationPath *string `json:"derivation_path"`
+				}{&account.AccountData, &account.Address, &account.DerivationPath}); err != nil {
+					continue
+				}
+
+				if matchesResult(account, result) {
+					found, foundKey = accounts, bytes.Clone(key)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil || found == nil {
+			return err
+		}
+
+		var account AccountData
+		if err := json.Unmarshal(found.Get(foundKey), &account); err != nil {
+			return fmt.Errorf("failed to parse account: %v", err)
+		}
+
+		ApplyCheckResult(&account, result)
+		return putAccount(found, foundKey, account)
+	})
+}
+
+func (s *BoltStore) SaveCheckResults(baseName string, results []*customTypes.ServerResponse) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts, err := getAccountsBucket(tx, baseName)
+		if err != nil {
+			return err
+		}
+
+		type update struct {
+			key     []byte
+			account AccountData
+		}
+		var updates []update
+
+		// Курсор нельзя использовать после изменения бакета, поэтому сначала собираем изменения
+		cursor := accounts.Cursor()
+		i := 0
+		for key, value := cursor.First(); key != nil && i < len(results); key, value = cursor.Next() {
+			result := results[i]
+			i++
+			if result == nil {
+				continue
+			}
+
+			var account AccountData
+			if err := json.Unmarshal(value, &account); err != nil {
+				return fmt.Errorf("failed to parse account: %v", err)
+			}
+
+			// Аккаунт заменили во время проверки
+			if !sameAccount(account, result) {
+				continue
+			}
+
+			ApplyCheckResult(&account, result)
+			updates = append(updates, update{key: bytes.Clone(key), account: account})
+		}
+
+		for _, u := range updates {
+			if err := putAccount(accounts, u.key, u.account); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func historyEntryKey(timestamp int64, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(timestamp))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
+
+func (s *BoltStore) AppendHistory(entries []HistoryEntry) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, entry := range entries {
+			addressBucket, err := tx.Bucket(historyBucket).CreateBucketIfNotExists([]byte(entry.Address))
+			if err != nil {
+				return err
+			}
+
+			seq, err := addressBucket.NextSequence()
+			if err != nil {
+				return err
+			}
+
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+
+			if err := addressBucket.Put(historyEntryKey(entry.Timestamp, seq), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) GetHistory(address string, from, to int64) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	if from < 0 {
+		from = 0
+	}
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		addressBucket := tx.Bucket(historyBucket).Bucket([]byte(address))
+		if addressBucket == nil {
+			return nil
+		}
+
+		cursor := addressBucket.Cursor()
+		for key, value := cursor.Seek(historyEntryKey(from, 0)); key != nil; key, value = cursor.Next() {
+			if int64(binary.BigEndian.Uint64(key[:8])) > to {
+				break
+			}
+
+			var entry HistoryEntry
+			if err := json.Unmarshal(value, &entry); err != nil {
+				return fmt.Errorf("failed to parse history entry: %v", err)
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
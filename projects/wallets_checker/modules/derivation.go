@@ -0,0 +1,104 @@
+package modules
+
+import (
+	"debank_checker_v3/utils"
+	"errors"
+	"fmt"
+)
+
+const maxDerivationCount = 1000
+
+// ErrPassphraseNeedsVault - BIP39 пароль сохраняется в каждом выведенном аккаунте, поэтому без
+// шифрования хранилища (-vault) импорт с паролем отклоняется.
+var ErrPassphraseNeedsVault = errors.New("bip39 passphrase would be stored in plaintext, run the server with -vault or use watch_only")
+
+// DerivationConfig - настройки вывода адресов из мнемоник при импорте.
+// Path - шаблон пути с x на месте индекса (m/44'/60'/x'/0/0) или пресет: metamask, ledger_live, legacy.
+// Каждый выведенный аккаунт хранит копию мнемоники и пароль: без -vault мнемоника лежит в базе
+// открытым текстом Count раз, как и при обычном импорте. Пароль без -vault не принимается.
+type DerivationConfig struct {
+	Path       string `json:"path"`
+	Start      int    `json:"start"`
+	Count      int    `json:"count"`
+	Passphrase string `json:"passphrase"`
+}
+
+// checkData - данные аккаунта для проверки. Выведенный аккаунт проверяется по адресу:
+// провайдеры выводят из мнемоники только адрес по пути по умолчанию.
+func (a AccountData) checkData() string {
+	if a.DerivationPath != "" {
+		return a.Address
+	}
+	return a.AccountData
+}
+
+// checkTarget - то, что передается провайдеру: checkData с префиксом сохраненного семейства,
+// чтобы провайдер не определял семейство заново по виду данных.
+func (a AccountData) checkTarget() string {
+	return utils.WithFamilyHint(a.ChainFamily, a.checkData())
+}
+
+// deriveAccounts превращает мнемонику в Count аккаунтов по шаблону пути.
+// Приватные ключи, адреса и аккаунты без настройки derivation импортируются как есть.
+func deriveAccounts(input InputAccountData, derivation *DerivationConfig) ([]AccountData, error) {
+	if derivation == nil || !utils.IsMnemonic(input.AccountData) {
+		return []AccountData{newAccountData(input)}, nil
+	}
+
+	count := derivation.Count
+	if count <= 0 {
+		count = 1
+	}
+	if count > maxDerivationCount || derivation.Start < 0 {
+		return nil, fmt.Errorf("derivation range must be within 0..%d accounts", maxDerivationCount)
+	}
+
+	addresses, fingerprint, err := utils.DeriveAddresses(input.AccountData, derivation.Passphrase, derivation.Path, derivation.Start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]AccountData, len(addresses))
+	for i, derived := range addresses {
+		account := newAccountData(input)
+		account.Address = derived.Address
+		account.DerivationPath = derived.Path
+		account.SeedFingerprint = fingerprint
+		account.Passphrase = derivation.Passphrase
+		accounts[i] = account
+	}
+	return accounts, nil
+}
+
+// importAccounts собирает аккаунты новой базы. В watch-only режиме секреты заменяются адресами.
+// encrypted - секреты базы шифруются хранилищем (VaultStore).
+// Прокси приводятся к виду scheme://host:port. Ошибка указывает индекс входного аккаунта и не содержит его данных.
+func importAccounts(inputs []InputAccountData, derivation *DerivationConfig, watchOnly bool, encrypted bool) ([]AccountData, error) {
+	// В watch-only режиме пароль отбрасывается вместе с мнемоникой
+	if derivation != nil && derivation.Passphrase != "" && !watchOnly && !encrypted {
+		return nil, ErrPassphraseNeedsVault
+	}
+
+	accounts := make([]AccountData, 0, len(inputs))
+
+	for i, input := range inputs {
+		proxies, err := utils.ParseProxies(input.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %v", i, err)
+		}
+		input.Proxy = proxies
+
+		derived, err := deriveAccounts(input, derivation)
+		if err != nil {
+			return nil, fmt.Errorf("account %d: %v", i, err)
+		}
+
+		for _, account := range derived {
+			if watchOnly && !stripSecret(&account) {
+				return nil, fmt.Errorf("account %d: wrong account credentials", i)
+			}
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
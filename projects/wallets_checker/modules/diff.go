@@ -0,0 +1,338 @@
+package modules
+
+import (
+	"context"
+	"debank_checker_v3/core"
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMinChangePercent = 5
+	defaultMinChangeUSD     = 10
+	defaultMinTokenUSD      = 1
+
+	webhookTimeout = 2 * time.Minute
+)
+
+// Для вебхуков свои повторы: получатель обычно наш же сервис, долго ждать его нет смысла
+var webhookRetryPolicy = core.RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    10 * time.Second,
+}
+
+// HoldingChange - изменение одного токена или позиции в пуле между двумя проверками.
+type HoldingChange struct {
+	Chain           string  `json:"chain"`
+	Name            string  `json:"name"`
+	ContractAddress string  `json:"contract_address,omitempty"`
+	Protocol        string  `json:"protocol,omitempty"` // Задан только для позиций в пулах
+	PreviousUSD     float64 `json:"previous_usd"`
+	CurrentUSD      float64 `json:"current_usd"`
+	ChangeUSD       float64 `json:"change_usd"`
+	ChangePercent   float64 `json:"change_percent"`
+}
+
+// WalletDiff - отличия результата проверки кошелька от предыдущего снимка в базе.
+type WalletDiff struct {
+	Index          int             `json:"index"`
+	Address        string          `json:"address"`
+	PreviousCheck  int64           `json:"previous_check"`
+	PreviousUSD    float64         `json:"previous_usd"`
+	CurrentUSD     float64         `json:"current_usd"`
+	ChangeUSD      float64         `json:"change_usd"`
+	ChangePercent  float64         `json:"change_percent"`
+	BalanceChanged bool            `json:"balance_changed"`
+	NewTokens      []HoldingChange `json:"new_tokens,omitempty"`
+	RemovedTokens  []HoldingChange `json:"removed_tokens,omitempty"`
+	ChangedTokens  []HoldingChange `json:"changed_tokens,omitempty"`
+}
+
+// WebhookPayload - тело запроса, которое получают вебхуки после проверки базы.
+type WebhookPayload struct {
+	Event     string       `json:"event"`
+	BaseName  string       `json:"base_name"`
+	Timestamp int64        `json:"timestamp"`
+	Changes   []WalletDiff `json:"changes"`
+}
+
+type alertThresholds struct {
+	percent  float64
+	usd      float64
+	tokenUSD float64
+}
+
+// newAlertThresholds подставляет значения по умолчанию для незаданных порогов.
+func newAlertThresholds(config customTypes.AlertConfig) alertThresholds {
+	thresholds := alertThresholds{
+		percent:  config.MinChangePercent,
+		usd:      config.MinChangeUSD,
+		tokenUSD: config.MinTokenUSD,
+	}
+	if thresholds.percent <= 0 {
+		thresholds.percent = defaultMinChangePercent
+	}
+	if thresholds.usd <= 0 {
+		thresholds.usd = defaultMinChangeUSD
+	}
+	if thresholds.tokenUSD <= 0 {
+		thresholds.tokenUSD = defaultMinTokenUSD
+	}
+	return thresholds
+}
+
+func (t alertThresholds) exceeded(changeUSD, changePercent float64) bool {
+	if changeUSD == 0 {
+		return false
+	}
+	return math.Abs(changeUSD) >= t.usd || math.Abs(changePercent) >= t.percent
+}
+
+func changePercent(previous, current float64) float64 {
+	if previous == 0 {
+		if current == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (current - previous) / math.Abs(previous) * 100
+}
+
+func usdValue(value *big.Float) float64 {
+	if value == nil {
+		return 0
+	}
+	usd, _ := value.Float64()
+	return usd
+}
+
+// collectHoldings раскладывает токены и позиции в пулах по ключу сеть + контракт (или имя).
+// Одинаковые позиции одного протокола суммируются.
+func collectHoldings(tokens customTypes.TokensData, pools customTypes.PoolsData) map[string]*HoldingChange {
+	holdings := make(map[string]*HoldingChange)
+
+	add := func(key string, holding HoldingChange, usd float64) {
+		if existing, exists := holdings[key]; exists {
+			existing.CurrentUSD += usd
+			return
+		}
+		holding.CurrentUSD = usd
+		holdings[key] = &holding
+	}
+
+	for _, chain := range tokens.Data {
+		for _, token := range chain.Tokens {
+			id := strings.ToLower(token.ContractAddress)
+			if id == "" {
+				id = token.Name
+			}
+			add("token|"+chain.ChainName+"|"+id, HoldingChange{
+				Chain:           chain.ChainName,
+				Name:            token.Name,
+				ContractAddress: token.ContractAddress,
+			}, usdValue(token.BalanceUSD))
+		}
+	}
+
+	for _, chain := range pools.Data {
+		for _, protocol := range chain.Protocols {
+			for _, pool := range protocol.Pools {
+				add("pool|"+chain.ChainName+"|"+protocol.ProtocolName+"|"+pool.Name, HoldingChange{
+					Chain:    chain.ChainName,
+					Name:     pool.Name,
+					Protocol: protocol.ProtocolName,
+				}, usdValue(pool.BalanceUSD))
+			}
+		}
+	}
+
+	return holdings
+}
+
+// diffScope - части снимка, которые можно сравнивать с новым результатом. Раздел, который не запрашивался,
+// и сети, по которым провайдер вернул ошибку, пропускаются: их позиции выглядели бы исчезнувшими.
+type diffScope struct {
+	tokens bool
+	pools  bool
+	// failedChains - сети из ошибок частичного результата (ошибки вида "<сеть> | ...")
+	failedChains map[string]bool
+	// unscopedFailure - ошибку не удалось отнести к сети, исчезновение позиций не сравнивается вообще
+	unscopedFailure bool
+}
+
+// newDiffScope определяет, что сравнивать. Флаги debank_config учитывают только DeBank провайдеры,
+// поэтому непустой раздел результата сравнивается и при выключенном флаге.
+func newDiffScope(result *customTypes.ServerResponse, config customTypes.DebankConfig, before, after map[string]*HoldingChange) diffScope {
+	scope := diffScope{
+		tokens:       config.ParseTokens || len(result.Tokens.Data) > 0,
+		pools:        config.ParsePools || len(result.Pools.Data) > 0,
+		failedChains: make(map[string]bool),
+	}
+
+	chains := make(map[string]bool)
+	for _, holdings := range []map[string]*HoldingChange{before, after} {
+		for _, holding := range holdings {
+			chains[holding.Chain] = true
+		}
+	}
+
+	for _, errText := range result.Errors {
+		chain, _, _ := strings.Cut(errText, " | ")
+		if chains[chain] {
+			scope.failedChains[chain] = true
+			continue
+		}
+		scope.unscopedFailure = true
+	}
+	return scope
+}
+
+// comparable проверяет, можно ли считать изменение или исчезновение позиции настоящим.
+func (s diffScope) comparable(key string, holding *HoldingChange) bool {
+	if strings.HasPrefix(key, "pool|") {
+		if !s.pools {
+			return false
+		}
+	} else if !s.tokens {
+		return false
+	}
+	return !s.unscopedFailure && !s.failedChains[holding.Chain]
+}
+
+func sortHoldingChanges(changes []HoldingChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		return math.Abs(changes[i].ChangeUSD) > math.Abs(changes[j].ChangeUSD)
+	})
+}
+
+// DiffAccount сравнивает результат проверки с сохраненным аккаунтом. Возвращает nil, если аккаунт
+// еще не проверялся или изменения не превышают порогов. Для частичного результата и незапрошенных
+// разделов сообщается только о новых позициях (diffScope).
+func DiffAccount(index int, previous AccountData, result *customTypes.ServerResponse, config customTypes.ConfigStruct) *WalletDiff {
+	if previous.LastCheck == 0 || result == nil {
+		return nil
+	}
+
+	thresholds := newAlertThresholds(config.AlertConfig)
+	diff := &WalletDiff{
+		Index:         index,
+		Address:       result.WalletAddress,
+		PreviousCheck: previous.LastCheck,
+		PreviousUSD:   previous.Balance,
+		CurrentUSD:    result.TotalBalance,
+		ChangeUSD:     result.TotalBalance - previous.Balance,
+		ChangePercent: changePercent(previous.Balance, result.TotalBalance),
+	}
+	diff.BalanceChanged = thresholds.exceeded(diff.ChangeUSD, diff.ChangePercent)
+
+	before := collectHoldings(previous.Tokens, previous.Pools)
+	after := collectHoldings(result.Tokens, result.Pools)
+	scope := newDiffScope(result, config.DebankConfig, before, after)
+
+	for key, current := range after {
+		change := *current
+		change.ChangeUSD = current.CurrentUSD
+
+		old, existed := before[key]
+		if !existed {
+			if current.CurrentUSD >= thresholds.tokenUSD {
+				change.ChangePercent = changePercent(0, current.CurrentUSD)
+				diff.NewTokens = append(diff.NewTokens, change)
+			}
+			continue
+		}
+		if !scope.comparable(key, current) {
+			continue
+		}
+
+		change.PreviousUSD = old.CurrentUSD
+		change.ChangeUSD = current.CurrentUSD - old.CurrentUSD
+		change.ChangePercent = changePercent(old.CurrentUSD, current.CurrentUSD)
+
+		// Колебания пыли не интересны, даже если в процентах они большие
+		if max(old.CurrentUSD, current.CurrentUSD) >= thresholds.tokenUSD && thresholds.exceeded(change.ChangeUSD, change.ChangePercent) {
+			diff.ChangedTokens = append(diff.ChangedTokens, change)
+		}
+	}
+
+	for key, old := range before {
+		if _, exists := after[key]; exists || old.CurrentUSD < thresholds.tokenUSD || !scope.comparable(key, old) {
+			continue
+		}
+		diff.RemovedTokens = append(diff.RemovedTokens, HoldingChange{
+			Chain:           old.Chain,
+			Name:            old.Name,
+			ContractAddress: old.ContractAddress,
+			Protocol:        old.Protocol,
+			PreviousUSD:     old.CurrentUSD,
+			ChangeUSD:       -old.CurrentUSD,
+			ChangePercent:   -100,
+		})
+	}
+
+	if !diff.BalanceChanged && len(diff.NewTokens) == 0 && len(diff.RemovedTokens) == 0 && len(diff.ChangedTokens) == 0 {
+		return nil
+	}
+
+	sortHoldingChanges(diff.NewTokens)
+	sortHoldingChanges(diff.RemovedTokens)
+	sortHoldingChanges(diff.ChangedTokens)
+	return diff
+}
+
+// ValidateAlertConfig проверяет, что у каждого вебхука есть адрес и секрет: алерты отправляются только подписанными.
+func ValidateAlertConfig(config customTypes.AlertConfig) error {
+	for i, webhook := range config.Webhooks {
+		if webhook.URL == "" {
+			return fmt.Errorf("alert webhook %d: url is required", i)
+		}
+		if webhook.Secret == "" {
+			return fmt.Errorf("alert webhook %d: secret is required to sign alerts", i)
+		}
+	}
+	return nil
+}
+
+// SendAlerts отправляет изменения базы на все вебхуки из конфига в фоне,
+// чтобы медленный получатель не задерживал ответ на проверку.
+func SendAlerts(baseName string, changes []WalletDiff, config customTypes.AlertConfig) {
+	if len(changes) == 0 || len(config.Webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		Event:     "wallet_changes",
+		BaseName:  baseName,
+		Timestamp: time.Now().Unix(),
+		Changes:   changes,
+	})
+	if err != nil {
+		log.Printf("%s | Failed To Marshal Alert: %v", baseName, err)
+		return
+	}
+
+	for _, webhook := range config.Webhooks {
+		if webhook.URL == "" {
+			continue
+		}
+
+		go func(webhook customTypes.WebhookConfig) {
+			ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+			defer cancel()
+
+			if err := core.PostWebhook(ctx, webhookRetryPolicy, webhook.URL, webhook.Secret, body); err != nil {
+				log.Printf("%s | Failed To Send Alert To %s: %v", baseName, webhook.URL, err)
+				return
+			}
+			log.Printf("%s | Sent %d Wallet Changes To %s", baseName, len(changes), webhook.URL)
+		}(webhook)
+	}
+}
@@ -0,0 +1,82 @@
+package modules
+
+import (
+	"debank_checker_v3/customTypes"
+	"math/big"
+	"testing"
+)
+
+func diffTestTokens(chains map[string]float64) customTypes.TokensData {
+	var data []customTypes.ChainTokens
+	for chain, usd := range chains {
+		data = append(data, customTypes.ChainTokens{ChainName: chain, Tokens: []customTypes.TokenData{
+			{Name: "TKN", ContractAddress: "0x" + chain, BalanceUSD: big.NewFloat(usd), Amount: big.NewFloat(1)},
+		}})
+	}
+	return customTypes.TokensData{Quantity: len(data), Data: data}
+}
+
+func diffTestPools(usd float64) customTypes.PoolsData {
+	return customTypes.PoolsData{Quantity: 1, Data: []customTypes.ChainPools{{
+		ChainName: "eth",
+		Protocols: []customTypes.ProtocolPools{{
+			ProtocolName: "Aave",
+			Pools:        []customTypes.PoolData{{Name: "USDC", BalanceUSD: big.NewFloat(usd), Amount: big.NewFloat(usd)}},
+		}},
+	}}}
+}
+
+func TestDiffAccountPartialResultKeepsFailedChains(t *testing.T) {
+	previous := AccountData{
+		Balance:   3000,
+		LastCheck: 1,
+		Tokens:    diffTestTokens(map[string]float64{"eth": 1000, "arb": 2000}),
+	}
+	config := customTypes.ConfigStruct{DebankConfig: customTypes.DebankConfig{ParseTokens: true}}
+
+	// Токены arb не получены: позиция не исчезла, а неизвестна
+	result := &customTypes.ServerResponse{
+		TotalBalance: 3000,
+		Tokens:       diffTestTokens(map[string]float64{"eth": 1000}),
+		Errors:       []string{"arb | token balances: upstream unavailable"},
+	}
+	if diff := DiffAccount(0, previous, result, config); diff != nil {
+		t.Fatalf("diff = %+v, want nil for holdings of failed chain", diff)
+	}
+
+	// Ошибку без сети нельзя отнести к разделу, поэтому исчезновение не сравнивается совсем
+	result.Errors = []string{"rate limited"}
+	if diff := DiffAccount(0, previous, result, config); diff != nil {
+		t.Fatalf("diff = %+v, want nil for unscoped partial error", diff)
+	}
+
+	// Без ошибок та же картина - настоящее исчезновение токенов
+	result.Errors = nil
+	diff := DiffAccount(0, previous, result, config)
+	if diff == nil || len(diff.RemovedTokens) != 1 || diff.RemovedTokens[0].Chain != "arb" {
+		t.Fatalf("diff = %+v, want arb token removed", diff)
+	}
+}
+
+func TestDiffAccountSkipsSectionsNotRequested(t *testing.T) {
+	previous := AccountData{
+		Balance:   1500,
+		LastCheck: 1,
+		Tokens:    diffTestTokens(map[string]float64{"eth": 1000}),
+		Pools:     diffTestPools(500),
+	}
+	result := &customTypes.ServerResponse{TotalBalance: 1500}
+
+	// Повторная проверка без parse_tokens и parse_pools: разделы пустые, потому что не запрашивались
+	config := customTypes.ConfigStruct{}
+	if diff := DiffAccount(0, previous, result, config); diff != nil {
+		t.Fatalf("diff = %+v, want nil for sections that were not requested", diff)
+	}
+
+	// Запрошены только пулы: пропавший пул - изменение, токены не сравниваются
+	config.DebankConfig.ParsePools = true
+	diff := DiffAccount(0, previous, result, config)
+	if diff == nil || len(diff.RemovedTokens) != 1 || diff.RemovedTokens[0].Protocol != "Aave" {
+		t.Fatalf("diff = %+v, want only Aave pool removed", diff)
+	}
+}
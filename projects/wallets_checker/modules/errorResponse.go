@@ -0,0 +1,56 @@
+package modules
+
+import (
+	"debank_checker_v3/core"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Коды ошибок обработчиков, не связанные с проверкой кошельков (их коды - core.ErrorCode).
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeMethodNotAllowed = "method_not_allowed"
+	CodeUnknownProvider  = "unknown_provider"
+	CodeNotFound         = "not_found"
+	CodeVaultLocked      = "vault_locked"
+)
+
+// ErrorResponse - тело ответа с ошибкой, одинаковое для всех обработчиков.
+type ErrorResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	Code    string `json:"code,omitempty"`
+}
+
+// WriteError отправляет ошибку в виде ErrorResponse с кодом, по которому фронт выбирает реакцию.
+func WriteError(w http.ResponseWriter, status int, code string, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Success: false,
+		Error:   err.Error(),
+		Code:    code,
+	})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter) {
+	WriteError(w, http.StatusMethodNotAllowed, CodeMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func writeInvalidRequest(w http.ResponseWriter, err error) {
+	WriteError(w, http.StatusBadRequest, CodeInvalidRequest, err)
+}
+
+// writeClassifiedError выбирает статус и код по ошибке: ошибки хранилища - по ним самим,
+// остальные - по категории ошибки проверки (core.HTTPStatus).
+func writeClassifiedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrBaseNotFound):
+		WriteError(w, http.StatusNotFound, CodeNotFound, err)
+	case errors.Is(err, ErrVaultLocked):
+		WriteError(w, http.StatusLocked, CodeVaultLocked, err)
+	default:
+		WriteError(w, core.HTTPStatus(err), core.ErrorCode(err), err)
+	}
+}
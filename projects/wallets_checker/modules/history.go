@@ -0,0 +1,279 @@
+package modules
+
+import (
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrPeriodTooLong = errors.New("period is too long")
+
+const (
+	historyDateLayout = "2006-01-02"
+	// Ограничение на длину ряда дневных агрегатов (10 лет)
+	maxHistoryDays = 3660
+)
+
+// HistoryEntry - снимок баланса кошелька после одной проверки.
+type HistoryEntry struct {
+	Address   string             `json:"address"`
+	Timestamp int64              `json:"timestamp"`
+	Provider  string             `json:"provider"`
+	TotalUSD  float64            `json:"total_usd"`
+	Chains    map[string]float64 `json:"chains"`
+}
+
+// DailyAggregate - суммарный баланс базы на конец дня (UTC).
+// Для каждого кошелька берется последний снимок, сделанный не позже конца дня.
+type DailyAggregate struct {
+	Date     string             `json:"date"`
+	TotalUSD float64            `json:"total_usd"`
+	Chains   map[string]float64 `json:"chains"`
+	Wallets  int                `json:"wallets"`
+}
+
+// historyKey нормализует адрес для хранения истории. EVM адреса регистронезависимы,
+// а base58/bech32 адреса других сетей сохраняются как есть.
+func historyKey(address string) string {
+	if strings.HasPrefix(address, "0x") || strings.HasPrefix(address, "0X") {
+		return strings.ToLower(address)
+	}
+	return address
+}
+
+// isValidHistoryKey отсекает все, что не похоже на адрес, в том числе mnemonic и пути к файлам.
+func isValidHistoryKey(key string) bool {
+	if key == "" || len(key) > 128 {
+		return false
+	}
+	for _, c := range key {
+		// '-' и '_' встречаются в адресах TON (base64url)
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func addUSD(totals map[string]float64, chainName string, value *big.Float) {
+	if value == nil {
+		return
+	}
+	usd, _ := value.Float64()
+	totals[chainName] += usd
+}
+
+// NewHistoryEntry собирает снимок из результата проверки: общий баланс и сумму по каждой сети.
+func NewHistoryEntry(provider string, result *customTypes.ServerResponse) HistoryEntry {
+	chains := make(map[string]float64)
+
+	for _, chain := range result.Tokens.Data {
+		for _, token := range chain.Tokens {
+			addUSD(chains, chain.ChainName, token.BalanceUSD)
+		}
+	}
+	for _, chain := range result.NFTs.Data {
+		for _, nft := range chain.Nfts {
+			addUSD(chains, chain.ChainName, nft.PriceUSD)
+		}
+	}
+	for _, chain := range result.Pools.Data {
+		for _, protocol := range chain.Protocols {
+			for _, pool := range protocol.Pools {
+				addUSD(chains, chain.ChainName, pool.BalanceUSD)
+			}
+		}
+	}
+
+	return HistoryEntry{
+		Address:   historyKey(result.WalletAddress),
+		Timestamp: time.Now().Unix(),
+		Provider:  provider,
+		TotalUSD:  result.TotalBalance,
+		Chains:    chains,
+	}
+}
+
+// RecordHistory добавляет в историю снимки всех успешных результатов. nil пропускаются.
+func RecordHistory(store Store, provider string, results []*customTypes.ServerResponse) {
+	entries := make([]HistoryEntry, 0, len(results))
+	for _, result := range results {
+		if result == nil || !isValidHistoryKey(historyKey(result.WalletAddress)) {
+			continue
+		}
+		entries = append(entries, NewHistoryEntry(provider, result))
+	}
+
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := store.AppendHistory(entries); err != nil {
+		log.Printf("Failed To Save Balance History: %v", err)
+	}
+}
+
+func startOfDay(timestamp int64) time.Time {
+	t := time.Unix(timestamp, 0).UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// DailyBaseHistory считает дневные агрегаты по всем кошелькам базы за период [from, to].
+// Если from равен 0, ряд начинается с дня самого раннего снимка.
+func DailyBaseHistory(store Store, baseName string, from, to int64) ([]DailyAggregate, error) {
+	base, err := store.GetBase(baseName)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var histories [][]HistoryEntry
+	earliest := int64(0)
+
+	for _, account := range base.Accounts {
+		key := historyKey(account.Address)
+		if seen[key] || !isValidHistoryKey(key) {
+			continue
+		}
+		seen[key] = true
+
+		// Снимки до from тоже нужны: ими заполняются дни без проверок
+		entries, err := store.GetHistory(key, 0, to)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) == 0 {
+			continue
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Timestamp < entries[j].Timestamp })
+		if earliest == 0 || entries[0].Timestamp < earliest {
+			earliest = entries[0].Timestamp
+		}
+		histories = append(histories, entries)
+	}
+
+	if len(histories) == 0 {
+		return []DailyAggregate{}, nil
+	}
+	if from == 0 {
+		from = earliest
+	}
+
+	firstDay, lastDay := startOfDay(from), startOfDay(to)
+	if days := int(lastDay.Sub(firstDay).Hours()/24) + 1; days > maxHistoryDays {
+		return nil, fmt.Errorf("%w: %d days, max %d", ErrPeriodTooLong, days, maxHistoryDays)
+	}
+
+	aggregates := make([]DailyAggregate, 0)
+	positions := make([]int, len(histories))
+
+	for day := firstDay; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+		endOfDay := day.AddDate(0, 0, 1).Unix()
+		aggregate := DailyAggregate{
+			Date:   day.Format(historyDateLayout),
+			Chains: make(map[string]float64),
+		}
+
+		for i, entries := range histories {
+			// Сдвигаемся к последнему снимку этого дня
+			for positions[i] < len(entries) && entries[positions[i]].Timestamp < endOfDay {
+				positions[i]++
+			}
+			if positions[i] == 0 {
+				continue
+			}
+
+			last := entries[positions[i]-1]
+			aggregate.Wallets++
+			aggregate.TotalUSD += last.TotalUSD
+			for chainName, usd := range last.Chains {
+				aggregate.Chains[chainName] += usd
+			}
+		}
+
+		aggregates = append(aggregates, aggregate)
+	}
+
+	return aggregates, nil
+}
+
+// parseHistoryPeriod читает from/to (unix секунды) из query. По умолчанию to - текущее время.
+func parseHistoryPeriod(r *http.Request) (int64, int64, error) {
+	from, to := int64(0), time.Now().Unix()
+
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid from: %s", value)
+		}
+		from = parsed
+	}
+
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid to: %s", value)
+		}
+		to = parsed
+	}
+
+	if from > to {
+		return 0, 0, fmt.Errorf("from is after to")
+	}
+	return from, to, nil
+}
+
+func (h *AccountHandler) HandleGetHistory(w http.ResponseWriter, r *http.Request) {
+	key := historyKey(strings.TrimSpace(r.PathValue("address")))
+	if !isValidHistoryKey(key) {
+		writeInvalidRequest(w, errors.New("invalid address"))
+		return
+	}
+
+	from, to, err := parseHistoryPeriod(r)
+	if err != nil {
+		writeInvalidRequest(w, err)
+		return
+	}
+
+	entries, err := h.store.GetHistory(key, from, to)
+	if err != nil {
+		writeClassifiedError(w, fmt.Errorf("failed to read history: %w", err))
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Timestamp < entries[j].Timestamp })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+func (h *AccountHandler) HandleGetBaseDailyHistory(w http.ResponseWriter, r *http.Request) {
+	from, to, err := parseHistoryPeriod(r)
+	if err != nil {
+		writeInvalidRequest(w, err)
+		return
+	}
+
+	aggregates, err := DailyBaseHistory(h.store, strings.TrimSpace(r.PathValue("name")), from, to)
+	if err != nil {
+		if errors.Is(err, ErrPeriodTooLong) {
+			writeInvalidRequest(w, err)
+			return
+		}
+		writeClassifiedError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(aggregates)
+}
@@ -0,0 +1,150 @@
+package modules
+
+import (
+	"debank_checker_v3/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxImportTextSize - ограничение размера текста импорта, чтобы разбор не занимал сервер надолго
+const maxImportTextSize = 16 << 20
+
+// ImportTextRequest - импорт из произвольного текста: CSV, пары addr:key, выгрузки других программ.
+// Proxy назначается всем найденным аккаунтам.
+type ImportTextRequest struct {
+	AccountsName string            `json:"accounts_name"`
+	Text         string            `json:"text"`
+	Proxy        []string          `json:"proxy"`
+	WatchOnly    bool              `json:"watch_only"`
+	Derivation   *DerivationConfig `json:"derivation,omitempty"`
+}
+
+// ImportPreviewItem - найденный аккаунт. Секрет в ответе скрыт, место в тексте указано строкой и столбцом.
+type ImportPreviewItem struct {
+	Line        int    `json:"line"`
+	Column      int    `json:"column"`
+	AccountData string `json:"account_data"`
+	Address     string `json:"address"`
+	ChainFamily string `json:"chain_family"`
+	Kind        string `json:"kind"`
+	Duplicates  int    `json:"duplicates"`
+}
+
+type ImportPreview struct {
+	AccountsName string              `json:"accounts_name"`
+	Found        []ImportPreviewItem `json:"found"`
+	Duplicates   int                 `json:"duplicates"`
+	// AccountsCount - сколько аккаунтов будет в базе с учетом derivation
+	AccountsCount  int   `json:"accounts_count"`
+	UnmatchedLines []int `json:"unmatched_lines"`
+}
+
+// prepareTextImport извлекает аккаунты из текста и собирает их так же, как /accounts/create.
+func prepareTextImport(req ImportTextRequest, watchOnly bool, encrypted bool) (*ImportPreview, []AccountData, error) {
+	extracted := utils.ExtractCredentials(req.Text)
+	credentials := extracted.Credentials
+	if len(credentials) == 0 {
+		return nil, nil, errors.New("no mnemonics, private keys or addresses found")
+	}
+
+	preview := &ImportPreview{
+		AccountsName:   req.AccountsName,
+		Found:          make([]ImportPreviewItem, len(credentials)),
+		UnmatchedLines: extracted.UnmatchedLines,
+	}
+	if preview.UnmatchedLines == nil {
+		preview.UnmatchedLines = []int{}
+	}
+
+	inputs := make([]InputAccountData, len(credentials))
+	for i, credential := range credentials {
+		inputs[i] = InputAccountData{AccountData: credential.Value, Proxy: req.Proxy}
+		preview.Found[i] = ImportPreviewItem{
+			Line:        credential.Line,
+			Column:      credential.Column,
+			AccountData: utils.RedactAccountData(credential.Value),
+			Address:     credential.Address,
+			ChainFamily: credential.Family,
+			Kind:        string(credential.Kind),
+			Duplicates:  credential.Duplicates,
+		}
+		preview.Duplicates += credential.Duplicates
+	}
+
+	accounts, err := importAccounts(inputs, req.Derivation, watchOnly, encrypted)
+	if err != nil {
+		return nil, nil, err
+	}
+	preview.AccountsCount = len(accounts)
+	return preview, accounts, nil
+}
+
+func decodeImportTextRequest(w http.ResponseWriter, r *http.Request) (ImportTextRequest, bool) {
+	var req ImportTextRequest
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return req, false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportTextSize)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidRequest(w, fmt.Errorf("invalid request body: %v", err))
+		return req, false
+	}
+	return req, true
+}
+
+// HandleImportPreview показывает, что будет импортировано из текста, ничего не сохраняя.
+func (h *AccountHandler) HandleImportPreview(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeImportTextRequest(w, r)
+	if !ok {
+		return
+	}
+
+	preview, _, err := prepareTextImport(req, h.watchOnly || req.WatchOnly, h.encrypted())
+	if err != nil {
+		writeInvalidRequest(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
+// HandleImportText создает базу из аккаунтов, найденных в тексте. Ответ совпадает с предпросмотром.
+func (h *AccountHandler) HandleImportText(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeImportTextRequest(w, r)
+	if !ok {
+		return
+	}
+
+	req.AccountsName = strings.TrimSpace(req.AccountsName)
+	if req.AccountsName == "" {
+		writeInvalidRequest(w, errors.New("base name is required"))
+		return
+	}
+
+	watchOnly := h.watchOnly || req.WatchOnly
+	preview, accounts, err := prepareTextImport(req, watchOnly, h.encrypted())
+	if err != nil {
+		writeInvalidRequest(w, err)
+		return
+	}
+
+	base := AccountsBase{
+		AccountsName: req.AccountsName,
+		Accounts:     accounts,
+		WatchOnly:    watchOnly,
+	}
+
+	if err := h.store.SaveBase(base); err != nil {
+		writeClassifiedError(w, fmt.Errorf("failed to save base: %w", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
@@ -0,0 +1,460 @@
+package modules
+
+import (
+	"context"
+	"crypto/rand"
+	"debank_checker_v3/core"
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Сколько хранить завершенные задачи в памяти
+const jobRetention = time.Hour
+
+// Размер буфера событий на одного подписчика. Если клиент не успевает читать,
+// события по кошелькам для него пропускаются, а сводные события приходят как обычно.
+const jobEventsBuffer = 256
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobCompleted JobStatus = "completed"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+type JobInfo struct {
+	ID         string            `json:"id"`
+	BaseName   string            `json:"base_name"`
+	Provider   string            `json:"provider"`
+	Status     JobStatus         `json:"status"`
+	Done       int               `json:"done"`
+	Total      int               `json:"total"`
+	Failed     int               `json:"failed"`
+	TotalUSD   float64           `json:"total_usd"`
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  int64             `json:"created_at"`
+	FinishedAt int64             `json:"finished_at,omitempty"`
+	Summary    *CheckBaseSummary `json:"summary,omitempty"`
+}
+
+const (
+	JobEventWallet   = "wallet"
+	JobEventProgress = "progress"
+	JobEventDone     = "done"
+)
+
+type JobEvent struct {
+	Name string
+	Data interface{}
+}
+
+type JobWalletEvent struct {
+	Index  int                         `json:"index"`
+	Wallet *customTypes.ServerResponse `json:"wallet"`
+	Error  string                      `json:"error,omitempty"`
+	Code   string                      `json:"code,omitempty"`
+}
+
+type Job struct {
+	mu          sync.Mutex
+	info        JobInfo
+	cancel      context.CancelFunc
+	subscribers map[chan JobEvent]struct{}
+}
+
+// Info возвращает копию состояния задачи, которую безопасно сериализовать.
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.info
+}
+
+func (j *Job) finished() bool {
+	switch j.info.Status {
+	case JobCompleted, JobFailed, JobCancelled:
+		return true
+	}
+	return false
+}
+
+// progressLocked - сводное состояние задачи без подробной сводки по ошибкам.
+func (j *Job) progressLocked() JobInfo {
+	progress := j.info
+	progress.Summary = nil
+	return progress
+}
+
+func (j *Job) Progress() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.progressLocked()
+}
+
+// publishLocked рассылает событие подписчикам без блокировки воркеров.
+func (j *Job) publishLocked(event JobEvent) {
+	for subscriber := range j.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe возвращает канал событий задачи. Канал закрывается после события done.
+// Для уже завершенной задачи сразу приходит done.
+func (j *Job) Subscribe() (<-chan JobEvent, func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	events := make(chan JobEvent, jobEventsBuffer)
+	if j.finished() {
+		events <- JobEvent{Name: JobEventDone, Data: j.info}
+		close(events)
+		return events, func() {}
+	}
+
+	j.subscribers[events] = struct{}{}
+	unsubscribe := func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		if _, exists := j.subscribers[events]; exists {
+			delete(j.subscribers, events)
+			close(events)
+		}
+	}
+	return events, unsubscribe
+}
+
+func (j *Job) onResult(index int, address string, result *customTypes.ServerResponse, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	event := JobWalletEvent{Index: index}
+
+	j.info.Done++
+	if err != nil || result == nil {
+		j.info.Failed++
+
+		event.Wallet = &customTypes.ServerResponse{WalletAddress: address}
+		event.Error, event.Code = "empty result", core.CodeInternal
+		if err != nil {
+			event.Error, event.Code = err.Error(), core.ErrorCode(err)
+		}
+	} else {
+		j.info.TotalUSD += result.TotalBalance
+		event.Wallet = result
+	}
+
+	event.Wallet = utils.RedactServerResponse(event.Wallet)
+	event.Wallet.WalletAddress = utils.RedactAccountData(event.Wallet.WalletAddress)
+	j.publishLocked(JobEvent{Name: JobEventWallet, Data: event})
+}
+
+type JobManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	store Store
+}
+
+func NewJobManager(store Store) *JobManager {
+	return &JobManager{jobs: make(map[string]*Job), store: store}
+}
+
+func generateJobID() string {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(id)
+}
+
+// pruneLocked удаляет завершенные задачи старше jobRetention. Вызывается под m.mu.
+func (m *JobManager) pruneLocked() {
+	threshold := time.Now().Add(-jobRetention).Unix()
+	for id, job := range m.jobs {
+		job.mu.Lock()
+		expired := job.finished() && job.info.FinishedAt < threshold
+		job.mu.Unlock()
+
+		if expired {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// Submit запускает проверку базы в фоне. Задача не зависит от HTTP запроса, который ее создал.
+// total - число аккаунтов базы на момент создания задачи, для прогресса.
+func (m *JobManager) Submit(baseName string, req CheckBaseRequest, total int) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	job := &Job{
+		info: JobInfo{
+			ID:        generateJobID(),
+			BaseName:  baseName,
+			Provider:  req.Type,
+			Status:    JobPending,
+			Total:     total,
+			CreatedAt: time.Now().Unix(),
+		},
+		cancel:      cancel,
+		subscribers: make(map[chan JobEvent]struct{}),
+	}
+
+	m.mu.Lock()
+	m.pruneLocked()
+	m.jobs[job.info.ID] = job
+	m.mu.Unlock()
+
+	go m.run(ctx, job, req)
+	return job
+}
+
+func (m *JobManager) run(ctx context.Context, job *Job, req CheckBaseRequest) {
+	defer job.cancel()
+
+	job.mu.Lock()
+	if job.info.Status == JobPending {
+		job.info.Status = JobRunning
+	}
+	job.mu.Unlock()
+
+	summary, err := CheckBase(ctx, m.store, job.info.BaseName, req, job.onResult)
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	job.info.Summary = summary
+	job.info.FinishedAt = time.Now().Unix()
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.info.Status = JobCancelled
+	case err != nil:
+		job.info.Status = JobFailed
+		job.info.Error = err.Error()
+	default:
+		job.info.Status = JobCompleted
+	}
+
+	if summary != nil {
+		job.info.Total = summary.Total
+		job.info.TotalUSD = summary.TotalUSD
+	}
+
+	job.publishLocked(JobEvent{Name: JobEventDone, Data: job.info})
+	for subscriber := range job.subscribers {
+		close(subscriber)
+	}
+	job.subscribers = make(map[chan JobEvent]struct{})
+
+	log.Printf("Job %s | %s | %s", job.info.ID, job.info.BaseName, job.info.Status)
+}
+
+func (m *JobManager) Get(id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, exists := m.jobs[id]
+	return job, exists
+}
+
+func (m *JobManager) List() []JobInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := make([]JobInfo, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		result = append(result, job.Info())
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt > result[j].CreatedAt
+	})
+	return result
+}
+
+// Cancel отменяет задачу. Уже завершенные задачи не меняются.
+func (m *JobManager) Cancel(id string) (*Job, bool) {
+	job, exists := m.Get(id)
+	if !exists {
+		return nil, false
+	}
+
+	job.cancel()
+	return job, true
+}
+
+type CreateJobRequest struct {
+	BaseName string `json:"base_name"`
+	CheckBaseRequest
+}
+
+type JobHandler struct {
+	manager *JobManager
+}
+
+func NewJobHandler(manager *JobManager) *JobHandler {
+	return &JobHandler{manager: manager}
+}
+
+func (h *JobHandler) HandleCreateJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	var req CreateJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeInvalidRequest(w, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	baseName := strings.TrimSpace(req.BaseName)
+	if status, code, err := validateCheckBaseRequest(h.manager.store, baseName, req.CheckBaseRequest); err != nil {
+		WriteError(w, status, code, err)
+		return
+	}
+
+	base, err := h.manager.store.GetBase(baseName)
+	if err != nil {
+		writeClassifiedError(w, fmt.Errorf("failed to read base: %w", err))
+		return
+	}
+
+	job := h.manager.Submit(baseName, req.CheckBaseRequest, len(base.Accounts))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job.Info())
+}
+
+func (h *JobHandler) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.manager.List())
+}
+
+func (h *JobHandler) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	job, exists := h.manager.Get(r.PathValue("id"))
+	if !exists {
+		WriteError(w, http.StatusNotFound, CodeNotFound, errors.New("job not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Info())
+}
+
+func (h *JobHandler) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	job, exists := h.manager.Cancel(r.PathValue("id"))
+	if !exists {
+		WriteError(w, http.StatusNotFound, CodeNotFound, errors.New("job not found"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Info())
+}
+
+// Как часто отправлять сводное событие progress в SSE поток
+const jobProgressInterval = 2 * time.Second
+
+func writeSSE(w http.ResponseWriter, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
+
+// HandleJobEvents отдает Server-Sent Events: wallet на каждый проверенный кошелек,
+// progress раз в jobProgressInterval и done по завершении задачи.
+func (h *JobHandler) HandleJobEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w)
+		return
+	}
+
+	job, exists := h.manager.Get(r.PathValue("id"))
+	if !exists {
+		WriteError(w, http.StatusNotFound, CodeNotFound, errors.New("job not found"))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		WriteError(w, http.StatusInternalServerError, core.CodeInternal, errors.New("streaming is not supported"))
+		return
+	}
+
+	events, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeSSE(w, JobEventProgress, job.Progress()); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(jobProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := writeSSE(w, JobEventProgress, job.Progress()); err != nil {
+				return
+			}
+		case event, ok := <-events:
+			if !ok {
+				// Событие done могло не поместиться в буфер подписчика
+				writeSSE(w, JobEventDone, job.Info())
+				flusher.Flush()
+				return
+			}
+			if err := writeSSE(w, event.Name, event.Data); err != nil {
+				return
+			}
+			if event.Name == JobEventDone {
+				flusher.Flush()
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
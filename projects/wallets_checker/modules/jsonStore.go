@@ -0,0 +1,389 @@
+package modules
+
+import (
+	"bytes"
+	"debank_checker_v3/customTypes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const tempFileSuffix = ".tmp"
+
+// JSONStore хранит каждую базу отдельным JSON файлом в директории <dataDir>/accounts,
+// а историю балансов - JSON Lines файлом на адрес в <dataDir>/history.
+// Изменения одной базы выполняются под ее блокировкой, а файл заменяется атомарно
+// через временный файл и rename, поэтому падение посреди записи не портит базу.
+type JSONStore struct {
+	dir        string
+	historyDir string
+	fsync      FsyncPolicy
+
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func NewJSONStore(dataDir string, fsync FsyncPolicy) (*JSONStore, error) {
+	dir := filepath.Join(dataDir, "accounts")
+	historyDir := filepath.Join(dataDir, "history")
+
+	for _, path := range []string{dir, historyDir} {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	// Временные файлы остаются только после падения во время записи, сама база при этом цела
+	tempFiles, _ := filepath.Glob(filepath.Join(dir, "*"+tempFileSuffix))
+	for _, path := range tempFiles {
+		log.Printf("Removing Unfinished Write: %s", path)
+		os.Remove(path)
+	}
+
+	return &JSONStore{
+		dir:        dir,
+		historyDir: historyDir,
+		fsync:      fsync,
+		locks:      make(map[string]*sync.Mutex),
+	}, nil
+}
+
+func (s *JSONStore) basePath(name string) string {
+	return filepath.Join(s.dir, name+".json")
+}
+
+// lockBase блокирует базу на время read-modify-write и возвращает функцию разблокировки.
+func (s *JSONStore) lockBase(name string) func() {
+	s.mu.Lock()
+	lock, exists := s.locks[name]
+	if !exists {
+		lock = &sync.Mutex{}
+		s.locks[name] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	return lock.Unlock
+}
+
+func (s *JSONStore) readFile(path string) (AccountsBase, error) {
+	var base AccountsBase
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return base, ErrBaseNotFound
+		}
+		return base, err
+	}
+
+	if err := json.Unmarshal(data, &base); err != nil {
+		return base, fmt.Errorf("failed to parse base %s: %v", filepath.Base(path), err)
+	}
+	return base, nil
+}
+
+// writeFile записывает базу во временный файл рядом с path и переименовывает его поверх path.
+// Читатели видят либо старую, либо новую версию файла целиком.
+func (s *JSONStore) writeFile(path string, base AccountsBase) error {
+	data, err := json.MarshalIndent(base, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal base %s: %v", base.AccountsName, err)
+	}
+
+	tempFile, err := os.CreateTemp(s.dir, filepath.Base(path)+".*"+tempFileSuffix)
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+
+	// До успешного rename временный файл удаляется при любой ошибке
+	renamed := false
+	defer func() {
+		if !renamed {
+			os.Remove(tempPath)
+		}
+	}()
+
+	if _, err = tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if s.fsync == FsyncAlways {
+		if err = tempFile.Sync(); err != nil {
+			tempFile.Close()
+			return err
+		}
+	}
+
+	if err = tempFile.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Chmod(tempPath, 0600); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tempPath, path); err != nil {
+		return err
+	}
+	renamed = true
+
+	if s.fsync == FsyncAlways {
+		return syncDir(s.dir)
+	}
+	return nil
+}
+
+// syncDir сохраняет на диск запись директории, иначе rename может потеряться при отключении питания.
+// На Windows директорию открыть для fsync нельзя, там rename и так журналируется NTFS.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (s *JSONStore) ListBases() ([]AccountsBase, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var bases []AccountsBase
+	for _, entry := range entries {
+		if !isBaseFile(entry.Name()) {
+			continue
+		}
+
+		base, err := s.readFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		bases = append(bases, base)
+	}
+	return bases, nil
+}
+
+func (s *JSONStore) GetBase(name string) (AccountsBase, error) {
+	return s.readFile(s.basePath(name))
+}
+
+func (s *JSONStore) BaseExists(name string) (bool, error) {
+	_, err := os.Stat(s.basePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *JSONStore) SaveBase(base AccountsBase) error {
+	defer s.lockBase(base.AccountsName)()
+
+	return s.writeFile(s.basePath(base.AccountsName), base)
+}
+
+func (s *JSONStore) DeleteBase(name string) error {
+	defer s.lockBase(name)()
+
+	err := os.Remove(s.basePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrBaseNotFound
+	}
+	return err
+}
+
+func (s *JSONStore) UpdateAccount(baseName string, index int, update func(account *AccountData)) error {
+	defer s.lockBase(baseName)()
+
+	path := s.basePath(baseName)
+	base, err := s.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(base.Accounts) {
+		return ErrAccountNotFound
+	}
+
+	update(&base.Accounts[index])
+	return s.writeFile(path, base)
+}
+
+func (s *JSONStore) DeleteAccount(baseName string, index int) error {
+	defer s.lockBase(baseName)()
+
+	path := s.basePath(baseName)
+	base, err := s.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	if index < 0 || index >= len(base.Accounts) {
+		return ErrAccountNotFound
+	}
+
+	base.Accounts = append(base.Accounts[:index], base.Accounts[index+1:]...)
+	return s.writeFile(path, base)
+}
+
+func (s *JSONStore) SaveCheckResult(result *customTypes.ServerResponse) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read accounts directory: %v", err)
+	}
+
+	// Ищем аккаунт во всех базах
+	for _, entry := range entries {
+		if !isBaseFile(entry.Name()) {
+			continue
+		}
+
+		found, err := s.saveCheckResultToBase(strings.TrimSuffix(entry.Name(), ".json"), result)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *JSONStore) saveCheckResultToBase(baseName string, result *customTypes.ServerResponse) (bool, error) {
+	defer s.lockBase(baseName)()
+
+	path := s.basePath(baseName)
+	base, err := s.readFile(path)
+	if err != nil {
+		return false, nil
+	}
+
+	for i := range base.Accounts {
+		if matchesResult(base.Accounts[i], result) {
+			ApplyCheckResult(&base.Accounts[i], result)
+			return true, s.writeFile(path, base)
+		}
+	}
+	return false, nil
+}
+
+func (s *JSONStore) SaveCheckResults(baseName string, results []*customTypes.ServerResponse) error {
+	defer s.lockBase(baseName)()
+
+	path := s.basePath(baseName)
+	base, err := s.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	if len(applyCheckResults(base.Accounts, results)) == 0 {
+		return nil
+	}
+	return s.writeFile(path, base)
+}
+
+func (s *JSONStore) historyPath(address string) string {
+	return filepath.Join(s.historyDir, address+".jsonl")
+}
+
+// AppendHistory дописывает снимки в конец файлов истории. Файл только дополняется,
+// поэтому при падении может потеряться лишь последняя недописанная строка.
+func (s *JSONStore) AppendHistory(entries []HistoryEntry) error {
+	byAddress := make(map[string][]HistoryEntry)
+	for _, entry := range entries {
+		if !isValidHistoryKey(entry.Address) {
+			return fmt.Errorf("invalid history address: %s", entry.Address)
+		}
+		byAddress[entry.Address] = append(byAddress[entry.Address], entry)
+	}
+
+	for address, addressEntries := range byAddress {
+		if err := s.appendAddressHistory(address, addressEntries); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONStore) appendAddressHistory(address string, entries []HistoryEntry) error {
+	defer s.lockBase("history/" + address)()
+
+	var data []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(s.historyPath(address), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if s.fsync == FsyncAlways {
+		if err = file.Sync(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
+}
+
+func (s *JSONStore) GetHistory(address string, from, to int64) ([]HistoryEntry, error) {
+	entries := make([]HistoryEntry, 0)
+	if !isValidHistoryKey(address) {
+		return entries, nil
+	}
+
+	data, err := os.ReadFile(s.historyPath(address))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry HistoryEntry
+		// Недописанная после падения строка пропускается
+		if err := json.Unmarshal(line, &entry); err != nil {
+			continue
+		}
+
+		if entry.Timestamp >= from && entry.Timestamp <= to {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func isBaseFile(name string) bool {
+	return filepath.Ext(name) == ".json"
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
@@ -0,0 +1,119 @@
+package modules
+
+import (
+	"debank_checker_v3/customTypes"
+	"debank_checker_v3/utils"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FsyncPolicy определяет, ждет ли запись сброса данных на диск.
+type FsyncPolicy string
+
+const (
+	// FsyncAlways - каждая запись сбрасывается на диск до ответа, переживает отключение питания.
+	FsyncAlways FsyncPolicy = "always"
+	// FsyncNever - запись остается в кеше ОС. Быстрее, но при отключении питания
+	// можно потерять последние изменения (файл базы при этом остается целым).
+	FsyncNever FsyncPolicy = "never"
+)
+
+func ParseFsyncPolicy(value string) (FsyncPolicy, error) {
+	switch policy := FsyncPolicy(value); policy {
+	case FsyncAlways, FsyncNever:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown fsync policy: %s", value)
+}
+
+var (
+	ErrBaseNotFound    = errors.New("base not found")
+	ErrAccountNotFound = errors.New("account not found")
+)
+
+// Store - хранилище баз аккаунтов и результатов их проверок.
+// Обработчики работают только через него и не знают, где лежат данные.
+type Store interface {
+	ListBases() ([]AccountsBase, error)
+	GetBase(name string) (AccountsBase, error)
+	BaseExists(name string) (bool, error)
+	// SaveBase создает базу или полностью заменяет существующую.
+	SaveBase(base AccountsBase) error
+	DeleteBase(name string) error
+
+	// UpdateAccount изменяет аккаунт по индексу внутри базы.
+	UpdateAccount(baseName string, index int, update func(account *AccountData)) error
+	DeleteAccount(baseName string, index int) error
+
+	// SaveCheckResult ищет аккаунт во всех базах по адресу или account_data и сохраняет результат.
+	SaveCheckResult(result *customTypes.ServerResponse) error
+	// SaveCheckResults сохраняет результаты проверки базы. results[i] относится к i-му аккаунту
+	// на момент чтения базы, nil пропускается.
+	SaveCheckResults(baseName string, results []*customTypes.ServerResponse) error
+
+	// AppendHistory добавляет снимки балансов в историю их адресов.
+	AppendHistory(entries []HistoryEntry) error
+	// GetHistory возвращает снимки адреса с timestamp в диапазоне [from, to].
+	GetHistory(address string, from, to int64) ([]HistoryEntry, error)
+
+	Close() error
+}
+
+func newAccountData(input InputAccountData) AccountData {
+	chainFamily, _ := utils.GetChainFamily(input.AccountData)
+	ensName := ""
+	if utils.IsEnsName(input.AccountData) {
+		ensName = strings.ToLower(input.AccountData)
+	}
+	return AccountData{
+		AccountData: input.AccountData,
+		ChainFamily: chainFamily,
+		EnsName:     ensName,
+		Address:     input.AccountData, // Используем account_data как адрес
+		Proxy:       input.Proxy,
+		Balance:     0,
+		LastCheck:   0,
+		Tokens: customTypes.TokensData{
+			Quantity: 0,
+			Data:     make([]customTypes.ChainTokens, 0),
+		},
+		NFTs: customTypes.NFTsData{
+			Quantity: 0,
+			Data:     make([]customTypes.ChainNfts, 0),
+		},
+		Pools: customTypes.PoolsData{
+			Quantity: 0,
+			Data:     make([]customTypes.ChainPools, 0),
+		},
+	}
+}
+
+// matchesResult проверяет, относится ли результат проверки к аккаунту.
+func matchesResult(account AccountData, result *customTypes.ServerResponse) bool {
+	return strings.EqualFold(account.Address, result.WalletAddress) ||
+		strings.EqualFold(account.checkTarget(), result.WalletData)
+}
+
+// sameAccount проверяет, что аккаунт не заменили, пока шла проверка. Зашифрованный секрет
+// нельзя сравнить с открытым, поэтому для него сравнивается адрес.
+func sameAccount(account AccountData, result *customTypes.ServerResponse) bool {
+	if IsVaultValue(account.checkData()) {
+		return strings.EqualFold(account.Address, result.WalletAddress)
+	}
+	return account.checkTarget() == result.WalletData
+}
+
+// applyCheckResults применяет результаты к аккаунтам базы. Аккаунт, который успели заменить
+// или сдвинуть во время проверки, пропускается. Возвращает индексы обновленных аккаунтов.
+func applyCheckResults(accounts []AccountData, results []*customTypes.ServerResponse) []int {
+	var updated []int
+	for i, result := range results {
+		if result == nil || i >= len(accounts) || !sameAccount(accounts[i], result) {
+			continue
+		}
+		ApplyCheckResult(&accounts[i], result)
+		updated = append(updated, i)
+	}
+	return updated
+}
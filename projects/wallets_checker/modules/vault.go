@@ -0,0 +1,356 @@
+package modules
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var (
+	ErrVaultLocked         = errors.New("vault is locked")
+	ErrVaultNotInitialized = errors.New("vault is not initialized")
+	ErrVaultInitialized    = errors.New("vault is already initialized")
+	ErrWrongPassphrase     = errors.New("wrong passphrase")
+)
+
+const (
+	// Зашифрованное значение: vault:v1:<base64(nonce || ciphertext)>
+	vaultValuePrefix = "vault:v1:"
+	vaultCheckValue  = "debank_checker_v3 vault"
+
+	vaultKeyLength  = 32
+	vaultSaltLength = 16
+
+	defaultUnlockTimeout = 15 * time.Minute
+	maxUnlockTimeout     = 24 * time.Hour
+)
+
+// vaultFile - параметры ключа, хранится в <dataDir>/vault.json. Самого ключа и пароля в нем нет,
+// Check - зашифрованная контрольная строка для проверки пароля при разблокировке.
+type vaultFile struct {
+	Version   int    `json:"version"`
+	KDF       string `json:"kdf"`
+	Salt      []byte `json:"salt"`
+	Time      uint32 `json:"time"`
+	MemoryKiB uint32 `json:"memory_kib"`
+	Threads   uint8  `json:"threads"`
+	Check     string `json:"check"`
+}
+
+// Vault шифрует секреты аккаунтов ключом, полученным из пароля через Argon2id.
+// Ключ живет только в памяти и стирается через timeout после разблокировки.
+type Vault struct {
+	path string
+
+	// unlockMu не дает запускать Argon2id параллельно: каждая попытка занимает 64 MiB
+	unlockMu sync.Mutex
+
+	mu         sync.RWMutex
+	file       *vaultFile
+	key        []byte
+	expiresAt  time.Time
+	generation int
+}
+
+type VaultStatus struct {
+	Initialized bool       `json:"initialized"`
+	Unlocked    bool       `json:"unlocked"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// OpenVault читает параметры хранилища ключа. Если файла нет, Vault создается неинициализированным.
+func OpenVault(path string) (*Vault, error) {
+	vault := &Vault{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return vault, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var file vaultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filepath.Base(path), err)
+	}
+	if file.Version != 1 || file.KDF != "argon2id" {
+		return nil, fmt.Errorf("unsupported vault version %d (%s)", file.Version, file.KDF)
+	}
+
+	vault.file = &file
+	return vault, nil
+}
+
+func deriveVaultKey(file *vaultFile, passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), file.Salt, file.Time, file.MemoryKiB, file.Threads, vaultKeyLength)
+}
+
+// Initialize задает пароль хранилища и записывает vault.json. Хранилище остается заблокированным.
+func (v *Vault) Initialize(passphrase string) error {
+	if passphrase == "" {
+		return fmt.Errorf("passphrase is required")
+	}
+
+	v.unlockMu.Lock()
+	defer v.unlockMu.Unlock()
+
+	v.mu.RLock()
+	initialized := v.file != nil
+	v.mu.RUnlock()
+	if initialized {
+		return ErrVaultInitialized
+	}
+
+	file := &vaultFile{
+		Version:   1,
+		KDF:       "argon2id",
+		Salt:      make([]byte, vaultSaltLength),
+		Time:      3,
+		MemoryKiB: 64 * 1024,
+		Threads:   4,
+	}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+
+	check, err := encryptValue(deriveVaultKey(file, passphrase), vaultCheckValue)
+	if err != nil {
+		return err
+	}
+	file.Check = check
+
+	data, err := json.MarshalIndent(file, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// Файл создается рядом и переименовывается, чтобы не оставить половину параметров после падения
+	tempPath := v.path + tempFileSuffix
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, v.path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	v.mu.Lock()
+	v.file = file
+	v.mu.Unlock()
+	return nil
+}
+
+// Unlock проверяет пароль и держит ключ в памяти timeout (по умолчанию 15 минут, не больше суток).
+func (v *Vault) Unlock(passphrase string, timeout time.Duration) (time.Time, error) {
+	if timeout <= 0 {
+		timeout = defaultUnlockTimeout
+	}
+	timeout = min(timeout, maxUnlockTimeout)
+
+	v.unlockMu.Lock()
+	defer v.unlockMu.Unlock()
+
+	v.mu.RLock()
+	file := v.file
+	v.mu.RUnlock()
+	if file == nil {
+		return time.Time{}, ErrVaultNotInitialized
+	}
+
+	key := deriveVaultKey(file, passphrase)
+	check, err := decryptValue(key, file.Check)
+	if err != nil || subtle.ConstantTimeCompare([]byte(check), []byte(vaultCheckValue)) != 1 {
+		return time.Time{}, ErrWrongPassphrase
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.wipeKey()
+	v.key = key
+	v.expiresAt = time.Now().Add(timeout)
+	v.generation++
+
+	// Таймер предыдущей разблокировки не должен заблокировать хранилище раньше срока
+	generation := v.generation
+	time.AfterFunc(timeout, func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		if v.generation == generation {
+			v.wipeKey()
+		}
+	})
+
+	return v.expiresAt, nil
+}
+
+func (v *Vault) Lock() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.generation++
+	v.wipeKey()
+}
+
+// wipeKey затирает ключ в памяти. Вызывается под v.mu.
+func (v *Vault) wipeKey() {
+	for i := range v.key {
+		v.key[i] = 0
+	}
+	v.key = nil
+	v.expiresAt = time.Time{}
+}
+
+func (v *Vault) Status() VaultStatus {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	status := VaultStatus{
+		Initialized: v.file != nil,
+		Unlocked:    v.key != nil,
+	}
+	if v.key != nil {
+		expiresAt := v.expiresAt
+		status.ExpiresAt = &expiresAt
+	}
+	return status
+}
+
+func (v *Vault) Unlocked() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.key != nil
+}
+
+func (v *Vault) Encrypt(plaintext string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return "", ErrVaultLocked
+	}
+	return encryptValue(v.key, plaintext)
+}
+
+func (v *Vault) Decrypt(value string) (string, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.key == nil {
+		return "", ErrVaultLocked
+	}
+	return decryptValue(v.key, value)
+}
+
+// IsVaultValue проверяет, зашифровано ли значение ключом хранилища.
+func IsVaultValue(value string) bool {
+	return strings.HasPrefix(value, vaultValuePrefix)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptValue(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return vaultValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func decryptValue(key []byte, value string) (string, error) {
+	if !IsVaultValue(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, vaultValuePrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid encrypted value: too short")
+	}
+
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+type UnlockVaultRequest struct {
+	Passphrase     string `json:"passphrase"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+type VaultHandler struct {
+	vault *Vault
+}
+
+func NewVaultHandler(vault *Vault) *VaultHandler {
+	return &VaultHandler{vault: vault}
+}
+
+func (h *VaultHandler) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.vault.Status())
+}
+
+func (h *VaultHandler) HandleUnlock(w http.ResponseWriter, r *http.Request) {
+	var req UnlockVaultRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	_, err := h.vault.Unlock(req.Passphrase, time.Duration(req.TimeoutSeconds)*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrWrongPassphrase):
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case errors.Is(err, ErrVaultNotInitialized):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, "Failed to unlock vault", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.vault.Status())
+}
+
+func (h *VaultHandler) HandleLock(w http.ResponseWriter, r *http.Request) {
+	h.vault.Lock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.vault.Status())
+}
@@ -0,0 +1,182 @@
+package modules
+
+import (
+	"debank_checker_v3/utils"
+	"log"
+)
+
+// VaultStore шифрует account_data поверх любого Store. Публичные адреса хранятся как есть,
+// mnemonic и приватные ключи - только в виде vault:v1:... Пока хранилище заблокировано,
+// базы читаются с зашифрованными значениями, а запись новых секретов возвращает ErrVaultLocked.
+type VaultStore struct {
+	Store
+	vault *Vault
+}
+
+func NewVaultStore(store Store, vault *Vault) *VaultStore {
+	return &VaultStore{Store: store, vault: vault}
+}
+
+func (s *VaultStore) Locked() bool {
+	return !s.vault.Unlocked()
+}
+
+// encryptAccount шифрует секрет аккаунта. Если адрес еще не известен (в нем лежит сам секрет),
+// он заменяется адресом, выведенным из секрета, иначе секрет остался бы в открытом виде.
+func (s *VaultStore) encryptAccount(account *AccountData) error {
+	if account.Passphrase != "" && !IsVaultValue(account.Passphrase) {
+		encrypted, err := s.vault.Encrypt(account.Passphrase)
+		if err != nil {
+			return err
+		}
+		account.Passphrase = encrypted
+	}
+
+	if IsVaultValue(account.AccountData) || utils.IsPublicAddress(account.AccountData) {
+		return nil
+	}
+
+	encrypted, err := s.vault.Encrypt(account.AccountData)
+	if err != nil {
+		return err
+	}
+
+	if account.Address == account.AccountData || !utils.IsPublicAddress(account.Address) {
+		address, err := utils.GetAccountAddress(account.AccountData)
+		if err != nil {
+			address = utils.RedactAccountData(account.AccountData)
+		}
+		account.Address = address
+	}
+
+	account.AccountData = encrypted
+	return nil
+}
+
+// decryptAccount расшифровывает секрет и BIP39 пароль аккаунта.
+func (s *VaultStore) decryptAccount(account *AccountData) error {
+	for _, value := range []*string{&account.AccountData, &account.Passphrase} {
+		if !IsVaultValue(*value) {
+			continue
+		}
+
+		plaintext, err := s.vault.Decrypt(*value)
+		if err != nil {
+			return err
+		}
+		*value = plaintext
+	}
+	return nil
+}
+
+// decryptAccounts расшифровывает секреты, если хранилище разблокировано.
+func (s *VaultStore) decryptAccounts(accounts []AccountData) {
+	if !s.vault.Unlocked() {
+		return
+	}
+
+	for i := range accounts {
+		if err := s.decryptAccount(&accounts[i]); err != nil {
+			log.Printf("%s | Failed To Decrypt Account: %v", accounts[i].Address, err)
+		}
+	}
+}
+
+func (s *VaultStore) ListBases() ([]AccountsBase, error) {
+	bases, err := s.Store.ListBases()
+	for i := range bases {
+		s.decryptAccounts(bases[i].Accounts)
+	}
+	return bases, err
+}
+
+func (s *VaultStore) GetBase(name string) (AccountsBase, error) {
+	base, err := s.Store.GetBase(name)
+	s.decryptAccounts(base.Accounts)
+	return base, err
+}
+
+func (s *VaultStore) SaveBase(base AccountsBase) error {
+	// Копия, чтобы не подменять аккаунты в структуре вызывающего
+	accounts := make([]AccountData, len(base.Accounts))
+	copy(accounts, base.Accounts)
+
+	for i := range accounts {
+		if err := s.encryptAccount(&accounts[i]); err != nil {
+			return err
+		}
+	}
+
+	base.Accounts = accounts
+	return s.Store.SaveBase(base)
+}
+
+func (s *VaultStore) UpdateAccount(baseName string, index int, update func(account *AccountData)) error {
+	// Изменение может заменить секрет, а зашифровать его без ключа нельзя
+	if !s.vault.Unlocked() {
+		return ErrVaultLocked
+	}
+
+	var updateErr error
+	err := s.Store.UpdateAccount(baseName, index, func(account *AccountData) {
+		original := *account
+		if err := s.decryptAccount(account); err != nil {
+			updateErr = err
+			*account = original
+			return
+		}
+
+		update(account)
+
+		// Шифруем заново, даже если секрет не менялся: update мог записать его в адрес
+		if err := s.encryptAccount(account); err != nil {
+			updateErr = err
+			*account = original
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// hasPlaintextSecret проверяет, остался ли в аккаунте незашифрованный секрет или BIP39 пароль.
+func hasPlaintextSecret(account AccountData) bool {
+	return !IsVaultValue(account.AccountData) && !utils.IsPublicAddress(account.AccountData) ||
+		account.Passphrase != "" && !IsVaultValue(account.Passphrase)
+}
+
+// MigrateToVault шифрует открытые секреты во всех базах. Хранилище должно быть разблокировано.
+// Возвращает число зашифрованных аккаунтов. Старые копии открытого текста могут остаться
+// в освобожденных блоках диска (и страницах файла bbolt) до их перезаписи.
+func MigrateToVault(store *VaultStore) (int, error) {
+	if store.Locked() {
+		return 0, ErrVaultLocked
+	}
+
+	// Читаем базы в обход расшифровки, чтобы отличить уже зашифрованные значения
+	bases, err := store.Store.ListBases()
+	if err != nil {
+		return 0, err
+	}
+
+	migrated := 0
+	for _, base := range bases {
+		plaintext := 0
+		for _, account := range base.Accounts {
+			if hasPlaintextSecret(account) {
+				plaintext++
+			}
+		}
+		if plaintext == 0 {
+			continue
+		}
+
+		if err := store.SaveBase(base); err != nil {
+			return migrated, err
+		}
+		migrated += plaintext
+		log.Printf("%s | Encrypted %d Accounts", base.AccountsName, plaintext)
+	}
+	return migrated, nil
+}
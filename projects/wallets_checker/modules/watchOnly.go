@@ -0,0 +1,123 @@
+package modules
+
+import (
+	"debank_checker_v3/utils"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// watchOnlyAccount оставляет от входных данных только адрес. Текст ошибки не содержит
+// исходного значения, чтобы секрет с опечаткой не попал в ответ или лог.
+// Если адрес сам по себе определяется как другое семейство (адрес Sui похож на ключ EVM),
+// к нему добавляется явное указание семейства.
+func watchOnlyAccount(input InputAccountData) (InputAccountData, error) {
+	info, err := utils.GetAccountInfo(input.AccountData)
+	if err != nil {
+		return InputAccountData{}, fmt.Errorf("wrong account credentials")
+	}
+	return InputAccountData{AccountData: utils.WatchOnlyAddress(info), Proxy: input.Proxy}, nil
+}
+
+// hasSecret проверяет, лежит ли в аккаунте что-то кроме публичного адреса.
+func hasSecret(account AccountData) bool {
+	return !utils.IsPublicAddress(account.AccountData) || account.Passphrase != ""
+}
+
+// FindBaseWithSecrets возвращает имя первой базы, в которой остались mnemonic или приватные ключи.
+func FindBaseWithSecrets(store Store) (string, error) {
+	bases, err := store.ListBases()
+	if err != nil {
+		return "", err
+	}
+
+	for _, base := range bases {
+		for _, account := range base.Accounts {
+			if hasSecret(account) {
+				return base.AccountsName, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// stripSecret заменяет секрет аккаунта его адресом. Для зашифрованного секрета берется адрес,
+// выведенный при шифровании. Возвращает false, если адрес получить не удалось.
+func stripSecret(account *AccountData) bool {
+	if !hasSecret(*account) {
+		return true
+	}
+
+	// Сохраненный адрес проверяется с учетом семейства: адрес Sui без него похож на ключ EVM
+	address := account.Address
+	if account.ChainFamily != "" {
+		address = account.ChainFamily + ":" + address
+	}
+
+	source := address
+	if !utils.IsPublicAddress(source) {
+		source = account.AccountData
+	}
+	info, err := utils.GetAccountInfo(source)
+	if err != nil {
+		return false
+	}
+	address = utils.WatchOnlyAddress(info)
+
+	account.AccountData = address
+	account.Address = address
+	account.Passphrase = ""
+	return true
+}
+
+// MigrateToWatchOnly безвозвратно удаляет секреты из всех баз и помечает базы как watch-only.
+// Если хотя бы из одного аккаунта не удается получить адрес, миграция не меняет ни одной базы
+// и возвращает ошибку со списком таких аккаунтов (база и индекс). Возвращает число очищенных аккаунтов.
+// Зашифрованные хранилищем значения обрабатываются без ключа, поэтому store должен быть без VaultStore.
+func MigrateToWatchOnly(store Store) (int, error) {
+	bases, err := store.ListBases()
+	if err != nil {
+		return 0, err
+	}
+
+	// Сначала проверяем все базы: секрет без адреса удалять нельзя, он потерялся бы безвозвратно
+	var unresolved []string
+	for _, base := range bases {
+		for index, account := range base.Accounts {
+			if hasSecret(account) && !stripSecret(&account) {
+				unresolved = append(unresolved, fmt.Sprintf("%s #%d", base.AccountsName, index))
+			}
+		}
+	}
+	if len(unresolved) > 0 {
+		return 0, fmt.Errorf("no address for %d accounts, bases are left unchanged: %s", len(unresolved), strings.Join(unresolved, ", "))
+	}
+
+	stripped := 0
+	for _, base := range bases {
+		changed := !base.WatchOnly
+		accounts := make([]AccountData, len(base.Accounts))
+		copy(accounts, base.Accounts)
+
+		for i := range accounts {
+			if !hasSecret(accounts[i]) {
+				continue
+			}
+			stripSecret(&accounts[i])
+			changed = true
+			stripped++
+		}
+
+		if !changed {
+			continue
+		}
+
+		base.Accounts = accounts
+		base.WatchOnly = true
+		if err := store.SaveBase(base); err != nil {
+			return stripped, err
+		}
+		log.Printf("%s | Watch-Only: %d Accounts", base.AccountsName, len(base.Accounts))
+	}
+	return stripped, nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Indexes = func() [256]int {
+	var indexes [256]int
+	for i := range indexes {
+		indexes[i] = -1
+	}
+	for i, c := range base58Alphabet {
+		indexes[c] = i
+	}
+	return indexes
+}()
+
+// base58Decode декодирует строку в алфавите Bitcoin/Solana. Ведущие '1' - нулевые байты.
+func base58Decode(input string) ([]byte, error) {
+	if input == "" {
+		return nil, fmt.Errorf("empty base58 string")
+	}
+
+	result := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i := 0; i < len(input); i++ {
+		index := base58Indexes[input[i]]
+		if index < 0 {
+			return nil, fmt.Errorf("invalid base58 character")
+		}
+		if index == 0 && zeros == i {
+			zeros++
+		}
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(index)))
+	}
+
+	return append(make([]byte, zeros), result.Bytes()...), nil
+}
+
+func base58Encode(input []byte) string {
+	zeros := 0
+	for zeros < len(input) && input[zeros] == 0 {
+		zeros++
+	}
+
+	value := new(big.Int).SetBytes(input)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var encoded []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < zeros; i++ {
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				checksum ^= generator[i]
+			}
+		}
+	}
+	return checksum
+}
+
+func bech32HrpExpand(hrp string) []byte {
+	result := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]>>5)
+	}
+	result = append(result, 0)
+	for i := 0; i < len(hrp); i++ {
+		result = append(result, hrp[i]&31)
+	}
+	return result
+}
+
+// bech32Decode разбирает bech32 строку (BIP173) и возвращает префикс и данные в 8-битных байтах.
+// Ограничение на длину 90 символов не применяется: ключи Sui длиннее.
+func bech32Decode(input string) (string, []byte, error) {
+	if strings.ToLower(input) != input && strings.ToUpper(input) != input {
+		return "", nil, fmt.Errorf("mixed case bech32 string")
+	}
+	input = strings.ToLower(input)
+
+	separator := strings.LastIndexByte(input, '1')
+	if separator < 1 || separator+7 > len(input) {
+		return "", nil, fmt.Errorf("invalid bech32 separator")
+	}
+
+	hrp := input[:separator]
+	data := make([]byte, 0, len(input)-separator-1)
+	for i := separator + 1; i < len(input); i++ {
+		index := strings.IndexByte(bech32Charset, input[i])
+		if index < 0 {
+			return "", nil, fmt.Errorf("invalid bech32 character")
+		}
+		data = append(data, byte(index))
+	}
+
+	if bech32Polymod(append(bech32HrpExpand(hrp), data...)) != 1 {
+		return "", nil, fmt.Errorf("invalid bech32 checksum")
+	}
+
+	decoded, err := convertBits(data[:len(data)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return hrp, decoded, nil
+}
+
+// convertBits перепаковывает группы бит (5-битные символы bech32 в байты и обратно).
+func convertBits(data []byte, fromBits, toBits uint, pad bool) ([]byte, error) {
+	accumulator := uint32(0)
+	bits := uint(0)
+	maxValue := uint32(1<<toBits) - 1
+
+	result := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	for _, value := range data {
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			result = append(result, byte(accumulator>>bits&maxValue))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			result = append(result, byte(accumulator<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || accumulator<<(toBits-bits)&maxValue != 0 {
+		return nil, fmt.Errorf("invalid bech32 padding")
+	}
+	return result, nil
+}
@@ -0,0 +1,293 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Семейства сетей. По семейству аккаунт направляется в провайдер, который его поддерживает.
+const (
+	FamilyEVM    = "evm"
+	FamilySolana = "solana"
+	FamilySui    = "sui"
+	FamilyTON    = "ton"
+	FamilyCosmos = "cosmos"
+)
+
+type AccountKind string
+
+const (
+	KindAddress    AccountKind = "address"
+	KindPrivateKey AccountKind = "private_key"
+	KindMnemonic   AccountKind = "mnemonic"
+	// KindName - ENS имя, адрес получается через NameResolver
+	KindName AccountKind = "ens_name"
+)
+
+// CosmosPrefixes - bech32 префиксы адресов сетей Cosmos SDK, которые принимаются при импорте.
+var CosmosPrefixes = map[string]bool{
+	"cosmos": true, "osmo": true, "celestia": true, "inj": true, "juno": true,
+	"stars": true, "akash": true, "axelar": true, "dydx": true, "sei": true,
+	"neutron": true, "stride": true, "kava": true, "evmos": true, "secret": true,
+	"terra": true, "dym": true, "noble": true, "saga": true, "archway": true,
+	"persistence": true, "regen": true, "umee": true, "kujira": true, "cro": true,
+}
+
+type AccountInfo struct {
+	Family  string
+	Kind    AccountKind
+	Address string
+}
+
+var (
+	suiAddressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{64}$`)
+	tonRawRegexp     = regexp.MustCompile(`^(0|-1):[0-9a-fA-F]{64}$`)
+)
+
+// accountCandidate - результат быстрого распознавания формата без вывода адреса из мнемоники.
+type accountCandidate struct {
+	family string
+	kind   AccountKind
+	// address вычисляется сразу для всего, кроме мнемоники
+	address string
+	value   string
+}
+
+// splitFamilyHint отделяет явное указание семейства вида "sui:0x...". Нужен для форматов,
+// которые нельзя отличить по виду: 0x + 64 hex без подсказки - приватный ключ EVM, адрес Sui
+// указывается только как "sui:0x...".
+func splitFamilyHint(target string) (string, string) {
+	family, value, found := strings.Cut(target, ":")
+	if !found {
+		return "", target
+	}
+	switch family {
+	case FamilyEVM, FamilySolana, FamilySui, FamilyTON, FamilyCosmos:
+		return family, value
+	}
+	return "", target
+}
+
+func identifyAccount(target string) (accountCandidate, error) {
+	hint, value := splitFamilyHint(strings.TrimSpace(target))
+
+	var detectors []func(string) (accountCandidate, bool)
+	switch hint {
+	case FamilyEVM:
+		detectors = []func(string) (accountCandidate, bool){identifyEvm}
+	case FamilySolana:
+		detectors = []func(string) (accountCandidate, bool){identifySolana}
+	case FamilySui:
+		detectors = []func(string) (accountCandidate, bool){identifySui}
+	case FamilyTON:
+		detectors = []func(string) (accountCandidate, bool){identifyTon}
+	case FamilyCosmos:
+		detectors = []func(string) (accountCandidate, bool){identifyCosmos}
+	default:
+		// identifyEvm идет первым: 0x + 64 hex без подсказки остается приватным ключом EVM, как до поддержки Sui
+		detectors = []func(string) (accountCandidate, bool){identifyEvm, identifySui, identifyCosmos, identifyTon, identifySolana}
+	}
+
+	for _, detect := range detectors {
+		if candidate, ok := detect(value); ok {
+			candidate.value = value
+			return candidate, nil
+		}
+	}
+
+	if hint != "" {
+		return accountCandidate{}, fmt.Errorf("wrong %s account credentials", hint)
+	}
+	return accountCandidate{}, fmt.Errorf("wrong account credentials")
+}
+
+func identifyEvm(value string) (accountCandidate, bool) {
+	if IsMnemonic(value) {
+		return accountCandidate{family: FamilyEVM, kind: KindMnemonic}, true
+	}
+	if valid, address := isPrivateKey(value); valid {
+		return accountCandidate{family: FamilyEVM, kind: KindPrivateKey, address: address}, true
+	}
+	if valid, address := isEthAddress(value); valid {
+		return accountCandidate{family: FamilyEVM, kind: KindAddress, address: address}, true
+	}
+	if IsEnsName(value) {
+		return accountCandidate{family: FamilyEVM, kind: KindName}, true
+	}
+	return accountCandidate{}, false
+}
+
+// identifySolana принимает base58 адрес (32 байта), base58 ключ (64 байта: seed + публичный ключ)
+// и JSON массив байт ключа из solana-keygen.
+func identifySolana(value string) (accountCandidate, bool) {
+	var keyBytes []byte
+	if strings.HasPrefix(value, "[") {
+		var numbers []int
+		if json.Unmarshal([]byte(value), &numbers) != nil {
+			return accountCandidate{}, false
+		}
+		for _, n := range numbers {
+			if n < 0 || n > 255 {
+				return accountCandidate{}, false
+			}
+			keyBytes = append(keyBytes, byte(n))
+		}
+	} else {
+		decoded, err := base58Decode(value)
+		if err != nil {
+			return accountCandidate{}, false
+		}
+		if len(decoded) == ed25519.PublicKeySize {
+			return accountCandidate{family: FamilySolana, kind: KindAddress, address: value}, true
+		}
+		keyBytes = decoded
+	}
+
+	if len(keyBytes) != ed25519.PrivateKeySize {
+		return accountCandidate{}, false
+	}
+
+	// Публичная половина ключа должна соответствовать seed, иначе это случайные 64 байта
+	public := ed25519.NewKeyFromSeed(keyBytes[:ed25519.SeedSize]).Public().(ed25519.PublicKey)
+	if !bytes.Equal(public, keyBytes[ed25519.SeedSize:]) {
+		return accountCandidate{}, false
+	}
+	return accountCandidate{family: FamilySolana, kind: KindPrivateKey, address: base58Encode(public)}, true
+}
+
+// identifySui принимает ключ suiprivkey1... (ed25519) и адрес 0x + 64 hex.
+// Адрес без указания "sui:" сюда не доходит, раньше его принимает identifyEvm как приватный ключ.
+func identifySui(value string) (accountCandidate, bool) {
+	if suiAddressRegexp.MatchString(value) {
+		return accountCandidate{family: FamilySui, kind: KindAddress, address: strings.ToLower(value)}, true
+	}
+
+	hrp, data, err := bech32Decode(value)
+	if err != nil || hrp != "suiprivkey" || len(data) != 1+ed25519.SeedSize || data[0] != 0 {
+		return accountCandidate{}, false
+	}
+
+	// Адрес Sui - blake2b-256 от флага схемы подписи и публичного ключа
+	public := ed25519.NewKeyFromSeed(data[1:]).Public().(ed25519.PublicKey)
+	hash := blake2b.Sum256(append([]byte{0}, public...))
+	return accountCandidate{family: FamilySui, kind: KindPrivateKey, address: "0x" + hex.EncodeToString(hash[:])}, true
+}
+
+func identifyCosmos(value string) (accountCandidate, bool) {
+	hrp, data, err := bech32Decode(value)
+	if err != nil || !CosmosPrefixes[hrp] {
+		return accountCandidate{}, false
+	}
+	// 20 байт - обычный аккаунт, 32 - модули и interchain accounts
+	if len(data) != 20 && len(data) != 32 {
+		return accountCandidate{}, false
+	}
+	return accountCandidate{family: FamilyCosmos, kind: KindAddress, address: strings.ToLower(value)}, true
+}
+
+// crc16 - CRC-16/XMODEM, которым защищены user-friendly адреса TON.
+func crc16(data []byte) uint16 {
+	crc := uint16(0)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// identifyTon принимает user-friendly адрес (48 символов base64/base64url) и raw адрес wc:hex.
+// Адрес приводится к base64url, raw адрес - к non-bounceable виду, как его показывают кошельки.
+func identifyTon(value string) (accountCandidate, bool) {
+	if tonRawRegexp.MatchString(value) {
+		workchain, _ := strconv.Atoi(value[:strings.IndexByte(value, ':')])
+		hash, _ := hex.DecodeString(value[strings.IndexByte(value, ':')+1:])
+
+		data := append([]byte{0x51, byte(int8(workchain))}, hash...)
+		data = binary.BigEndian.AppendUint16(data, crc16(data))
+		return accountCandidate{family: FamilyTON, kind: KindAddress, address: base64.URLEncoding.EncodeToString(data)}, true
+	}
+
+	if len(value) != 48 {
+		return accountCandidate{}, false
+	}
+
+	normalized := strings.NewReplacer("+", "-", "/", "_").Replace(value)
+	data, err := base64.URLEncoding.DecodeString(normalized)
+	if err != nil || len(data) != 36 {
+		return accountCandidate{}, false
+	}
+
+	// 0x11 - bounceable, 0x51 - non-bounceable, старший бит - testnet
+	if tag := data[0] &^ 0x80; tag != 0x11 && tag != 0x51 {
+		return accountCandidate{}, false
+	}
+	if binary.BigEndian.Uint16(data[34:]) != crc16(data[:34]) {
+		return accountCandidate{}, false
+	}
+	return accountCandidate{family: FamilyTON, kind: KindAddress, address: normalized}, true
+}
+
+// WithFamilyHint добавляет к данным аккаунта префикс "<семейство>:", если его еще нет,
+// чтобы сохраненное при импорте семейство не определялось заново по виду данных.
+func WithFamilyHint(family string, target string) string {
+	if hint, _ := splitFamilyHint(target); family == "" || hint != "" {
+		return target
+	}
+	return family + ":" + target
+}
+
+// WatchOnlyAddress возвращает адрес аккаунта в виде, который снова распознается как то же семейство.
+// Адрес, который без подсказки определился бы иначе (Sui 0x + 64 hex), получает префикс "<семейство>:".
+func WatchOnlyAddress(info AccountInfo) string {
+	if family, err := GetChainFamily(info.Address); err == nil && family == info.Family {
+		return info.Address
+	}
+	return info.Family + ":" + info.Address
+}
+
+// GetChainFamily определяет семейство сети аккаунта без вывода адреса (дешево даже для мнемоники и ENS имен).
+func GetChainFamily(target string) (string, error) {
+	candidate, err := identifyAccount(target)
+	return candidate.family, err
+}
+
+// GetAccountInfo определяет семейство, тип данных и адрес аккаунта.
+func GetAccountInfo(target string) (AccountInfo, error) {
+	candidate, err := identifyAccount(target)
+	if err != nil {
+		return AccountInfo{}, err
+	}
+
+	info := AccountInfo{Family: candidate.family, Kind: candidate.kind, Address: candidate.address}
+	switch candidate.kind {
+	case KindMnemonic:
+		valid, address := isMnemonic(candidate.value)
+		if !valid {
+			return AccountInfo{}, fmt.Errorf("wrong account credentials")
+		}
+		info.Address = address
+	case KindName:
+		address, err := resolveName(candidate.value)
+		if err != nil {
+			return AccountInfo{}, err
+		}
+		info.Address = address
+	}
+	return info, nil
+}
@@ -2,57 +2,22 @@ package utils
 
 import (
 	"encoding/hex"
-	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/tyler-smith/go-bip32"
-	"github.com/tyler-smith/go-bip39"
 	"regexp"
 	"strings"
 )
 
 func isMnemonic(input string) (bool, string) {
-	if !bip39.IsMnemonicValid(input) {
+	if !IsMnemonic(input) {
 		return false, ""
 	}
 
-	seed, err := bip39.NewSeedWithErrorChecking(input, "")
+	path, _ := ExpandDerivationPath(DefaultDerivationPath, 0)
+	address, err := DeriveAddress(input, "", path)
 	if err != nil {
 		return false, ""
 	}
-
-	masterKey, err := bip32.NewMasterKey(seed)
-	if err != nil {
-		return false, ""
-	}
-
-	purpose, err := masterKey.NewChildKey(bip32.FirstHardenedChild + 44)
-	if err != nil {
-		return false, ""
-	}
-	coinType, err := purpose.NewChildKey(bip32.FirstHardenedChild + 60)
-	if err != nil {
-		return false, ""
-	}
-	account, err := coinType.NewChildKey(bip32.FirstHardenedChild + 0)
-	if err != nil {
-		return false, ""
-	}
-	change, err := account.NewChildKey(0)
-	if err != nil {
-		return false, ""
-	}
-	addressKey, err := change.NewChildKey(0)
-	if err != nil {
-		return false, ""
-	}
-
-	privateKey, err := crypto.ToECDSA(addressKey.Key)
-	if err != nil {
-		return false, ""
-	}
-
-	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
 	return true, address
 }
 
@@ -96,18 +61,12 @@ func isEthAddress(input string) (bool, string) {
 	return loweredInput == strings.ToLower(address.Hex()), address.Hex()
 }
 
+// GetAccountAddress возвращает адрес аккаунта любого поддерживаемого семейства сетей.
+// ENS имена (name.eth) разрешаются через резолвер, заданный SetNameResolver.
 func GetAccountAddress(target string) (string, error) {
-	if valid, address := isMnemonic(target); valid {
-		return address, nil
-	}
-
-	if valid, address := isPrivateKey(target); valid {
-		return address, nil
-	}
-
-	if valid, address := isEthAddress(target); valid {
-		return address, nil
+	info, err := GetAccountInfo(target)
+	if err != nil {
+		return "", err
 	}
-
-	return "", fmt.Errorf("wrong account credentials")
+	return info.Address, nil
 }
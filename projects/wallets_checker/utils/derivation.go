@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/tyler-smith/go-bip32"
+	"github.com/tyler-smith/go-bip39"
+)
+
+// DefaultDerivationPath - путь MetaMask и большинства EVM кошельков. x заменяется индексом аккаунта.
+const DefaultDerivationPath = "m/44'/60'/0'/0/x"
+
+// DerivationPathPresets - распространенные схемы деривации EVM адресов.
+var DerivationPathPresets = map[string]string{
+	"metamask":    DefaultDerivationPath,
+	"ledger_live": "m/44'/60'/x'/0/0",
+	"legacy":      "m/44'/60'/0'/x",
+}
+
+type DerivedAddress struct {
+	Path    string
+	Address string
+}
+
+// IsMnemonic проверяет, что строка - валидная BIP39 мнемоника.
+func IsMnemonic(input string) bool {
+	return bip39.IsMnemonicValid(input)
+}
+
+// ExpandDerivationPath подставляет index вместо x в шаблоне пути или пресете.
+// Шаблон без x допустим только для index 0.
+func ExpandDerivationPath(template string, index int) (string, error) {
+	if preset, exists := DerivationPathPresets[template]; exists {
+		template = preset
+	}
+	if template == "" {
+		template = DefaultDerivationPath
+	}
+
+	if strings.Count(template, "x") > 1 {
+		return "", fmt.Errorf("derivation path must contain at most one x: %s", template)
+	}
+	if !strings.Contains(template, "x") && index != 0 {
+		return "", fmt.Errorf("derivation path has no x placeholder: %s", template)
+	}
+
+	path := strings.Replace(template, "x", strconv.Itoa(index), 1)
+	if _, err := parseDerivationPath(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// parseDerivationPath разбирает путь вида m/44'/60'/0'/0/0 в индексы BIP32.
+func parseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(path, "/")
+	if len(parts) < 2 || parts[0] != "m" {
+		return nil, fmt.Errorf("invalid derivation path: %s", path)
+	}
+
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		hardened := strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h")
+		part = strings.TrimRight(part, "'h")
+
+		parsed, err := strconv.ParseUint(part, 10, 32)
+		index := uint32(parsed)
+		if err != nil || index >= bip32.FirstHardenedChild {
+			return nil, fmt.Errorf("invalid derivation path: %s", path)
+		}
+
+		if hardened {
+			index += bip32.FirstHardenedChild
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+func deriveKeyAddress(masterKey *bip32.Key, path string) (string, error) {
+	indexes, err := parseDerivationPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	key := masterKey
+	for _, index := range indexes {
+		if key, err = key.NewChildKey(index); err != nil {
+			return "", err
+		}
+	}
+
+	privateKey, err := crypto.ToECDSA(key.Key)
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), nil
+}
+
+func newMasterKey(mnemonic string, passphrase string) (*bip32.Key, error) {
+	seed, err := bip39.NewSeedWithErrorChecking(mnemonic, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mnemonic")
+	}
+	return bip32.NewMasterKey(seed)
+}
+
+// DeriveAddress выводит адрес мнемоники по пути с BIP39 паролем (пустой - без пароля).
+func DeriveAddress(mnemonic string, passphrase string, path string) (string, error) {
+	masterKey, err := newMasterKey(mnemonic, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return deriveKeyAddress(masterKey, path)
+}
+
+// DeriveAddresses выводит count адресов по шаблону пути, начиная с индекса start.
+// Вместе с адресами возвращается отпечаток мастер-ключа, общий для всех адресов одной мнемоники
+// с одним паролем. Он не раскрывает мнемонику и связывает выведенные аккаунты с их сидом.
+func DeriveAddresses(mnemonic string, passphrase string, template string, start int, count int) ([]DerivedAddress, string, error) {
+	masterKey, err := newMasterKey(mnemonic, passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Отпечаток родителя первого потомка - hash160 публичного мастер-ключа
+	firstChild, err := masterKey.NewChildKey(bip32.FirstHardenedChild)
+	if err != nil {
+		return nil, "", err
+	}
+	fingerprint := hex.EncodeToString(firstChild.FingerPrint)
+
+	addresses := make([]DerivedAddress, 0, count)
+	for index := start; index < start+count; index++ {
+		path, err := ExpandDerivationPath(template, index)
+		if err != nil {
+			return nil, "", err
+		}
+
+		address, err := deriveKeyAddress(masterKey, path)
+		if err != nil {
+			return nil, "", err
+		}
+		addresses = append(addresses, DerivedAddress{Path: path, Address: address})
+	}
+	return addresses, fingerprint, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var ensNameRegexp = regexp.MustCompile(`^(?i)([a-z0-9-]+\.)+eth$`)
+
+// NameResolver превращает ENS имя в адрес. Регистрируется пакетом core, которому доступен RPC.
+type NameResolver func(name string) (string, error)
+
+var (
+	nameResolverMu sync.RWMutex
+	nameResolver   NameResolver
+)
+
+// IsEnsName проверяет, похожа ли строка на ENS имя (name.eth, sub.name.eth).
+func IsEnsName(value string) bool {
+	return ensNameRegexp.MatchString(value)
+}
+
+func SetNameResolver(resolver NameResolver) {
+	nameResolverMu.Lock()
+	defer nameResolverMu.Unlock()
+	nameResolver = resolver
+}
+
+func resolveName(name string) (string, error) {
+	nameResolverMu.RLock()
+	resolver := nameResolver
+	nameResolverMu.RUnlock()
+
+	if resolver == nil {
+		return "", fmt.Errorf("ens resolution is not configured")
+	}
+
+	address, err := resolver(strings.ToLower(name))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %v", name, err)
+	}
+	return address, nil
+}
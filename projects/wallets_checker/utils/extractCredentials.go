@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ExtractedCredential - мнемоника, приватный ключ или адрес, найденный в произвольном тексте.
+// Line и Column (с 1, в символах) указывают первое вхождение, Duplicates - сколько раз
+// тот же аккаунт встретился еще.
+type ExtractedCredential struct {
+	Value      string
+	Family     string
+	Kind       AccountKind
+	Address    string
+	Line       int
+	Column     int
+	Duplicates int
+}
+
+// ExtractResult - результат разбора текста. UnmatchedLines - номера непустых строк, в которых ничего не найдено.
+type ExtractResult struct {
+	Credentials    []ExtractedCredential
+	UnmatchedLines []int
+}
+
+// Длины мнемоник BIP39 от длинной к короткой: из 24 слов нельзя выделять первые 12
+var mnemonicLengths = []int{24, 21, 18, 15, 12}
+
+var (
+	// Форматы, внутри которых есть символы-разделители, ищутся до разбиения строки на слова
+	tonRawPattern    = regexp.MustCompile(`-?[0-9]:[0-9a-fA-F]{64}`)
+	byteArrayPattern = regexp.MustCompile(`\[\s*\d{1,3}(?:\s*,\s*\d{1,3}){63}\s*\]`)
+	mnemonicWord     = regexp.MustCompile(`^[a-z]+$`)
+)
+
+const credentialSeparators = ",;|\"'`=()[]{}<>:"
+
+type textToken struct {
+	value  string
+	offset int
+	// hint - семейство из префикса вида "sui:" перед значением
+	hint string
+}
+
+func isCredentialSeparator(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(credentialSeparators, r)
+}
+
+func isHexRune(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F'
+}
+
+// splitCredentialTokens разбивает строку на слова по пробелам и типичным разделителям CSV, JSON и пар addr:key.
+// Слово-семейство, за которым сразу идет ":", становится подсказкой для следующего слова.
+func splitCredentialTokens(line string) []textToken {
+	var tokens []textToken
+	hint := ""
+	hintOffset := 0
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		// Точка в конце предложения не входит ни в один формат
+		value := strings.TrimRight(line[start:end], ".")
+		offset := start
+		start = -1
+		if value == "" {
+			return
+		}
+
+		if end < len(line) && line[end] == ':' {
+			if family, _ := splitFamilyHint(value + ":"); family != "" {
+				hint = family
+				hintOffset = offset
+				return
+			}
+		}
+		if hint != "" {
+			offset = hintOffset
+		}
+		tokens = append(tokens, textToken{value: value, offset: offset, hint: hint})
+		hint = ""
+	}
+
+	for offset, r := range line {
+		if isCredentialSeparator(r) {
+			flush(offset)
+			continue
+		}
+		if start < 0 {
+			start = offset
+		}
+	}
+	flush(len(line))
+	return tokens
+}
+
+// extractSpans находит в строке raw адреса TON и ключи Solana в виде JSON массива и затирает их
+// пробелами той же длины, чтобы позиции остальных слов не сдвинулись.
+func extractSpans(line string, found func(value string, offset int, candidate accountCandidate)) string {
+	masked := []byte(line)
+
+	for _, match := range tonRawPattern.FindAllStringIndex(line, -1) {
+		start, end := match[0], match[1]
+		if start > 0 && isHexRune(rune(line[start-1])) || end < len(line) && isHexRune(rune(line[end])) {
+			continue
+		}
+		if candidate, ok := identifyTon(line[start:end]); ok {
+			found(line[start:end], start, candidate)
+			copy(masked[start:end], strings.Repeat(" ", end-start))
+		}
+	}
+
+	for _, match := range byteArrayPattern.FindAllStringIndex(line, -1) {
+		start, end := match[0], match[1]
+		if candidate, ok := identifySolana(line[start:end]); ok {
+			found(line[start:end], start, candidate)
+			copy(masked[start:end], strings.Repeat(" ", end-start))
+		}
+	}
+
+	return string(masked)
+}
+
+// extractMnemonics ищет мнемоники среди подряд идущих слов из строчных букв и возвращает
+// слова, которые в них не вошли.
+func extractMnemonics(tokens []textToken, found func(value string, offset int, candidate accountCandidate)) []textToken {
+	rest := make([]textToken, 0, len(tokens))
+
+	for i := 0; i < len(tokens); {
+		runEnd := i
+		for runEnd < len(tokens) && tokens[runEnd].hint == "" && mnemonicWord.MatchString(tokens[runEnd].value) {
+			runEnd++
+		}
+		if runEnd-i < mnemonicLengths[len(mnemonicLengths)-1] {
+			rest = append(rest, tokens[i])
+			i++
+			continue
+		}
+
+		matched := false
+		for _, length := range mnemonicLengths {
+			if i+length > runEnd {
+				continue
+			}
+			words := make([]string, length)
+			for j := range words {
+				words[j] = tokens[i+j].value
+			}
+			if phrase := strings.Join(words, " "); IsMnemonic(phrase) {
+				found(phrase, tokens[i].offset, accountCandidate{family: FamilyEVM, kind: KindMnemonic})
+				i += length
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			rest = append(rest, tokens[i])
+			i++
+		}
+	}
+	return rest
+}
+
+// credentialKey - ключ для поиска одинаковых значений, записанных по-разному (регистр, 0x, подсказка семейства).
+// Ключ и адрес однозначно определяют друг друга, поэтому сравниваются по адресу.
+func credentialKey(value string, candidate accountCandidate) string {
+	if candidate.kind == KindMnemonic {
+		return candidate.family + "|" + string(candidate.kind) + "|" + value
+	}
+	return candidate.family + "|" + string(candidate.kind) + "|" + strings.ToLower(candidate.address)
+}
+
+// ExtractCredentials находит в каждой строке текста все мнемоники, приватные ключи и адреса
+// поддерживаемых семейств сетей и убирает повторы. Адрес, для которого в тексте есть секрет
+// (пары addr:key), считается повтором этого секрета. ENS имена не извлекаются.
+// Как и при обычном импорте, 0x + 64 hex без подсказки "sui:" считается приватным ключом EVM.
+func ExtractCredentials(text string) ExtractResult {
+	var credentials []ExtractedCredential
+	var unmatchedLines []int
+	byKey := make(map[string]int)
+
+	for lineIndex, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		lineFound := 0
+
+		found := func(value string, offset int, candidate accountCandidate) {
+			key := credentialKey(value, candidate)
+			if index, exists := byKey[key]; exists {
+				credentials[index].Duplicates++
+				lineFound++
+				return
+			}
+
+			info, err := GetAccountInfo(value)
+			if err != nil {
+				return
+			}
+			lineFound++
+
+			credentials = append(credentials, ExtractedCredential{
+				Value:   value,
+				Family:  info.Family,
+				Kind:    info.Kind,
+				Address: info.Address,
+				Line:    lineIndex + 1,
+				Column:  utf8.RuneCountInString(line[:offset]) + 1,
+			})
+			byKey[key] = len(credentials) - 1
+		}
+
+		masked := extractSpans(line, found)
+		for _, token := range extractMnemonics(splitCredentialTokens(masked), found) {
+			value := token.value
+			if token.hint != "" {
+				value = token.hint + ":" + value
+			}
+			if candidate, err := identifyAccount(value); err == nil && candidate.kind != KindName {
+				found(value, token.offset, candidate)
+			}
+		}
+
+		if lineFound == 0 && strings.TrimSpace(line) != "" {
+			unmatchedLines = append(unmatchedLines, lineIndex+1)
+		}
+	}
+
+	// Адреса, у которых есть секрет, убираются: аккаунт и так будет импортирован по секрету
+	secrets := make(map[string]int)
+	for i, credential := range credentials {
+		key := credential.Family + "|" + strings.ToLower(credential.Address)
+		if _, exists := secrets[key]; !exists && credential.Kind != KindAddress {
+			secrets[key] = i
+		}
+	}
+
+	redundant := make([]bool, len(credentials))
+	for i, credential := range credentials {
+		if credential.Kind != KindAddress {
+			continue
+		}
+		if index, exists := secrets[credential.Family+"|"+strings.ToLower(credential.Address)]; exists {
+			credentials[index].Duplicates += credential.Duplicates + 1
+			redundant[i] = true
+		}
+	}
+
+	result := make([]ExtractedCredential, 0, len(credentials))
+	for i, credential := range credentials {
+		if !redundant[i] {
+			result = append(result, credential)
+		}
+	}
+
+	// Форматы из extractSpans находятся раньше остальных, поэтому порядок восстанавливается по позиции
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Line != result[j].Line {
+			return result[i].Line < result[j].Line
+		}
+		return result[i].Column < result[j].Column
+	})
+	return ExtractResult{Credentials: result, UnmatchedLines: unmatchedLines}
+}
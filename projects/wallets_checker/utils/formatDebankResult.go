@@ -19,13 +19,14 @@ func FormatResult(accountData string,
 	totalUsdBalance float64,
 	tokenBalances map[string][]customTypes.TokenBalancesResultData,
 	nftBalances map[string][]customTypes.NftBalancesResultData,
-	poolsData map[string]map[string][]customTypes.PoolBalancesResultData) {
+	poolsData map[string]map[string][]customTypes.PoolBalancesResultData,
+	debankConfig customTypes.DebankConfig) {
 	var formattedResult string
 
 	formattedResult += fmt.Sprintf("==================== Address: %s (%f $)\n", accountAddress, totalUsdBalance)
 	formattedResult += fmt.Sprintf("==================== Account Data: %s\n", accountData)
 
-	if ConfigFile.DebankConfig.ParseTokens == true && len(tokenBalances) > 0 {
+	if debankConfig.ParseTokens == true && len(tokenBalances) > 0 {
 		totalTokens := 0
 
 		for _, tokens := range tokenBalances {
@@ -50,7 +51,7 @@ func FormatResult(accountData string,
 		formattedResult += "\n"
 	}
 
-	if ConfigFile.DebankConfig.ParseNfts == true && len(nftBalances) > 0 {
+	if debankConfig.ParseNfts == true && len(nftBalances) > 0 {
 		totalNFTs := 0
 
 		for _, balances := range nftBalances {
@@ -75,7 +76,7 @@ func FormatResult(accountData string,
 		formattedResult += "\n"
 	}
 
-	if ConfigFile.DebankConfig.ParsePools == true && len(poolsData) > 0 {
+	if debankConfig.ParsePools == true && len(poolsData) > 0 {
 		totalPools := 0
 
 		for _, chainPools := range poolsData {
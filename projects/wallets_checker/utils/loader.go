@@ -1,5 +0,0 @@
-package utils
-
-import "debank_checker_v3/customTypes"
-
-var ConfigFile customTypes.ConfigStruct
@@ -77,3 +77,21 @@ func isPort(s string) bool {
 	match, _ := regexp.MatchString(`^\d+$`, s)
 	return match
 }
+
+// ParseProxies приводит список прокси к виду scheme://host:port. Текст ошибки содержит только
+// номер прокси в списке: сам прокси может содержать логин и пароль.
+func ParseProxies(proxies []string) ([]string, error) {
+	if len(proxies) == 0 {
+		return proxies, nil
+	}
+
+	parsed := make([]string, len(proxies))
+	for i, proxy := range proxies {
+		normalized, err := ParseProxy(strings.TrimSpace(proxy))
+		if err != nil {
+			return nil, fmt.Errorf("proxy %d: invalid proxy format", i)
+		}
+		parsed[i] = normalized
+	}
+	return parsed, nil
+}
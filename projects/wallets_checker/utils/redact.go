@@ -0,0 +1,30 @@
+package utils
+
+import "debank_checker_v3/customTypes"
+
+const redactedValue = "[redacted]"
+
+// RedactAccountData скрывает mnemonic и приватные ключи. Публичный адрес возвращается как есть.
+func RedactAccountData(accountData string) string {
+	if IsPublicAddress(accountData) {
+		return accountData
+	}
+	return redactedValue
+}
+
+// RedactServerResponse возвращает копию ответа без секретов, пригодную для отправки наружу.
+func RedactServerResponse(response *customTypes.ServerResponse) *customTypes.ServerResponse {
+	if response == nil {
+		return nil
+	}
+
+	redacted := *response
+	redacted.WalletData = RedactAccountData(response.WalletData)
+	return &redacted
+}
+
+// IsPublicAddress проверяет, что account_data - публичный адрес (любого семейства сетей) или ENS имя, а не секрет.
+func IsPublicAddress(accountData string) bool {
+	candidate, err := identifyAccount(accountData)
+	return err == nil && (candidate.kind == KindAddress || candidate.kind == KindName)
+}